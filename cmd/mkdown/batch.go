@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+// batchJob is a single markdown file to convert as part of a batch run.
+type batchJob struct {
+	input  string
+	output string
+}
+
+// outputIndex maps the markdown files converted in a run to the HTML files
+// written for them, so that links between them point at the right pages.
+type outputIndex map[string]string
+
+// set replaces the index with the outputs of jobs.
+func (x outputIndex) set(jobs []batchJob) {
+	clear(x)
+	for _, job := range jobs {
+		if abs, err := filepath.Abs(job.input); err == nil {
+			x[abs] = job.output
+		}
+	}
+}
+
+// lookup returns the HTML file written for the markdown file at path, or ""
+// if it is not part of the run.
+func (x outputIndex) lookup(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return x[abs]
+}
+
+// isMarkdownFile reports whether path has a markdown extension.
+func isMarkdownFile(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasSuffix(lower, ".md") || strings.HasSuffix(lower, ".markdown")
+}
+
+// hasGlobMeta reports whether path contains any glob metacharacters.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// isBatchInput reports whether the given positional arguments require batch
+// mode: more than one input, a directory, or a glob pattern.
+func isBatchInput(inputs []string) bool {
+	if len(inputs) != 1 {
+		return len(inputs) > 1
+	}
+	if hasGlobMeta(inputs[0]) {
+		return true
+	}
+	info, err := os.Stat(inputs[0])
+	return err == nil && info.IsDir()
+}
+
+// inputRoot returns the closest directory containing every input: the
+// input directories, the roots of glob patterns and the directories of
+// input files. Wikilinks are resolved against it. It returns "" when that
+// is the filesystem root, so that each file's own directory is used rather
+// than the whole filesystem being searched.
+func inputRoot(inputs []string) string {
+	var common []string
+	for i, input := range inputs {
+		dir := input
+		if hasGlobMeta(input) {
+			dir, _ = splitGlob(input)
+		} else if info, err := os.Stat(input); err != nil || !info.IsDir() {
+			dir = filepath.Dir(input)
+		}
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return "."
+		}
+
+		parts := strings.Split(abs, string(filepath.Separator))
+		if i == 0 {
+			common = parts
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	root := strings.Join(common, string(filepath.Separator))
+	if root == "" || filepath.Dir(root) == root {
+		return ""
+	}
+	// Keep the root relative when the inputs are, as it shows in warnings.
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, root); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return root
+}
+
+// collectJobs expands directories and glob patterns into a list of markdown
+// files. When outputDir is set, each output path mirrors the file's location
+// relative to the directory or glob root it was found under; otherwise the
+// HTML file is written next to its source.
+func collectJobs(inputs []string, outputDir string) ([]batchJob, error) {
+	var jobs []batchJob
+	seen := make(map[string]bool)
+
+	add := func(root, path string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		jobs = append(jobs, batchJob{input: path, output: batchOutputPath(root, path, outputDir)})
+	}
+
+	for _, input := range inputs {
+		switch {
+		case hasGlobMeta(input):
+			root, matches, err := expandGlob(input)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern '%s': %w", input, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no markdown files match '%s'", input)
+			}
+			for _, match := range matches {
+				add(root, match)
+			}
+		default:
+			info, err := os.Stat(input)
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("file '%s' not found", input)
+			} else if err != nil {
+				return nil, err
+			}
+
+			if !info.IsDir() {
+				if !isMarkdownFile(input) {
+					return nil, fmt.Errorf("input file '%s' must be a markdown file (.md or .markdown)", input)
+				}
+				add(filepath.Dir(input), input)
+				continue
+			}
+
+			files, err := walkMarkdown(input)
+			if err != nil {
+				return nil, err
+			}
+			for _, file := range files {
+				add(input, file)
+			}
+		}
+	}
+
+	return jobs, nil
+}
+
+// batchOutputPath returns the HTML path for input, mirrored under outputDir
+// relative to root when outputDir is set.
+func batchOutputPath(root, input, outputDir string) string {
+	htmlName := strings.TrimSuffix(input, filepath.Ext(input)) + ".html"
+	if outputDir == "" {
+		return htmlName
+	}
+
+	rel, err := filepath.Rel(root, htmlName)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(htmlName)
+	}
+	return filepath.Join(outputDir, rel)
+}
+
+// walkMarkdown returns every markdown file under dir in lexical order,
+// skipping hidden directories such as .git.
+func walkMarkdown(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isMarkdownFile(path) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// expandGlob expands pattern into matching markdown files. In addition to
+// the filepath.Match syntax, a "**" path segment matches zero or more
+// directories. The returned root is the leading part of the pattern that
+// contains no metacharacters.
+func expandGlob(pattern string) (string, []string, error) {
+	root, segments := splitGlob(pattern)
+
+	// Validate every segment up front so a malformed pattern is reported
+	// rather than silently matching nothing.
+	for _, seg := range segments {
+		if seg == "**" {
+			continue
+		}
+		if _, err := filepath.Match(seg, ""); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || !isMarkdownFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if matchSegments(segments, strings.Split(filepath.ToSlash(rel), "/")) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	sort.Strings(matches)
+	return root, matches, nil
+}
+
+// splitGlob splits pattern into its leading directory without
+// metacharacters and the remaining slash-separated segments.
+func splitGlob(pattern string) (string, []string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	var rootSegs []string
+	for len(segments) > 0 && !hasGlobMeta(segments[0]) {
+		rootSegs = append(rootSegs, segments[0])
+		segments = segments[1:]
+	}
+
+	root := "."
+	if len(rootSegs) > 0 {
+		root = filepath.FromSlash(strings.Join(rootSegs, "/"))
+		if root == "" {
+			root = "/"
+		}
+	}
+	return root, segments
+}
+
+// matchSegments matches path segments against pattern segments, where "**"
+// matches any number of segments.
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(rest, path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// runBatch converts every job with the shared converter, printing a line per
+// file, and returns the number of files that failed.
+func runBatch(converter *mkdown.Converter, jobs []batchJob) int {
+	failed := 0
+	for _, job := range jobs {
+		if err := converter.Convert(job.input, job.output); err != nil {
+			failed++
+			fmt.Fprintf(os.Stderr, "✗ Failed: %s: %v\n", job.input, err)
+			continue
+		}
+		fmt.Printf("✓ Generated: %s\n", job.output)
+	}
+	return failed
+}
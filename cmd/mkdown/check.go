@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+// externalWorkers is how many external URLs are fetched at once.
+const externalWorkers = 8
+
+// runCheck implements "mkdown check [inputs...] [flags]", which reports
+// broken links, images, anchors and footnotes as path:line diagnostics and
+// exits non-zero if there are any.
+func runCheck(args []string) {
+	var (
+		inputs   []string
+		external bool
+		timeout  = 10 * time.Second
+		flags    = config{}
+	)
+
+	for i := 0; i < len(args); i++ {
+		if next, ok := parseConverterFlag(args, i, flags); ok {
+			i = next
+			continue
+		}
+
+		arg := args[i]
+		switch arg {
+		case "--external":
+			external = true
+		case "--timeout":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires an argument\n", arg)
+				os.Exit(1)
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "Error: invalid timeout '%s': use a duration such as 10s\n", args[i])
+				os.Exit(1)
+			}
+			timeout = d
+		case "-h", "--help":
+			fmt.Println("Usage: mkdown check [input.md | dir | glob]... [flags]")
+			fmt.Println("\nCheck that relative links and images point at existing files, that #anchors")
+			fmt.Println("match a heading, that wikilinks name a page and that footnotes are defined.")
+			fmt.Println("Inputs default to the current directory.")
+			fmt.Println("\nFlags:")
+			fmt.Println("  --external           Also fetch http(s) links and report those that fail")
+			fmt.Println("                       (by default they are only listed)")
+			fmt.Println("  --timeout <duration>")
+			fmt.Println("                       Time allowed for each external link (default: 10s)")
+			fmt.Println("  -h, --help           Show this help")
+			os.Exit(0)
+		default:
+			if len(arg) > 1 && arg[0] == '-' {
+				fmt.Fprintf(os.Stderr, "Error: Unknown flag: %s\n", arg)
+				os.Exit(1)
+			}
+			inputs = append(inputs, arg)
+		}
+	}
+	if len(inputs) == 0 {
+		inputs = []string{"."}
+	}
+
+	jobs, err := collectJobs(inputs, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := loadConfig(configStartDir(inputs[0]), flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts := cfg.converterOptions()
+	opts.WikiLinkRoot = inputRoot(inputs)
+	if opts.IncludeRoot == "" {
+		opts.IncludeRoot = opts.WikiLinkRoot
+	}
+	checker := mkdown.NewLinkChecker(mkdown.NewConverterWithOptions(opts))
+
+	// broken holds the inputs with problems, including those in the files
+	// they include.
+	broken := make(map[string]bool)
+	var (
+		links      []mkdown.ExternalLink
+		linkInputs []string // the input each link was found checking
+	)
+	for _, job := range jobs {
+		report, err := checker.Check(job.input)
+		if err != nil {
+			broken[job.input] = true
+			fmt.Println(err)
+			continue
+		}
+		for _, p := range report.Problems {
+			broken[job.input] = true
+			fmt.Println(p)
+		}
+		for _, link := range report.External {
+			links = append(links, link)
+			linkInputs = append(linkInputs, job.input)
+		}
+	}
+
+	switch {
+	case external:
+		failures := fetchExternalLinks(links, timeout)
+		for i, link := range links {
+			if failure := failures[link.URL]; failure != "" {
+				broken[linkInputs[i]] = true
+				fmt.Println(mkdown.LinkProblem{Path: link.Path, Line: link.Line, Message: link.URL + ": " + failure})
+			}
+		}
+	case len(links) > 0:
+		fmt.Println("\nExternal links not checked (use --external to fetch them):")
+		for _, link := range links {
+			fmt.Printf("  %s:%d: %s\n", link.Path, link.Line, link.URL)
+		}
+	}
+
+	if len(broken) > 0 {
+		fmt.Fprintf(os.Stderr, "\nError: broken links in %d of %d files\n", len(broken), len(jobs))
+		os.Exit(1)
+	}
+	fmt.Printf("\n✓ Checked %d files, no broken links\n", len(jobs))
+}
+
+// fetchExternalLinks fetches the URL of each link once and returns why
+// each one failed, or "" for those that did not.
+func fetchExternalLinks(links []mkdown.ExternalLink, timeout time.Duration) map[string]string {
+	client := &http.Client{Timeout: timeout}
+	results := make(map[string]string)
+	var mu sync.Mutex
+	urls := make(chan string)
+
+	var wg sync.WaitGroup
+	for i := 0; i < externalWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range urls {
+				failure := fetchURL(client, u)
+				mu.Lock()
+				results[u] = failure
+				mu.Unlock()
+			}
+		}()
+	}
+	seen := make(map[string]bool)
+	for _, link := range links {
+		if !seen[link.URL] {
+			seen[link.URL] = true
+			urls <- link.URL
+		}
+	}
+	close(urls)
+	wg.Wait()
+	return results
+}
+
+// fetchURL requests u and describes why it failed, or returns "". Servers
+// that answer HEAD requests with an error are asked again with GET.
+func fetchURL(client *http.Client, u string) string {
+	var failure string
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequest(method, u, nil)
+		if err != nil {
+			return err.Error()
+		}
+		req.Header.Set("User-Agent", "mkdown/"+version)
+		resp, err := client.Do(req)
+		if err != nil {
+			// The URL is already part of the message.
+			if urlErr, ok := err.(*url.Error); ok {
+				err = urlErr.Err
+			}
+			return err.Error()
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			return ""
+		}
+		failure = resp.Status
+	}
+	return failure
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/ekinertac/mkdown/mkdown"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the name of both the global config file in the home
+// directory and the per-project config file.
+const configFileName = ".mkdown.yml"
+
+// configKeys lists every supported setting in display order.
+var configKeys = []string{
+	"theme", "mermaid", "math", "copy_buttons", "offline", "inline_images", "inline_css", "inline_max_size",
+	"toc", "toc_min_depth", "toc_max_depth", "includes", "include_root", "wikilinks", "rewrite_links", "output_dir", "highlight_style", "css", "css_mode", "template",
+}
+
+// boolKeys are settings that accept true or false and whose flags take no
+// argument; --no-<flag> sets them to false. math also accepts the name of a
+// math output format.
+var boolKeys = map[string]bool{"mermaid": true, "math": true, "copy_buttons": true, "offline": true, "inline_images": true, "inline_css": true, "toc": true, "includes": true, "wikilinks": true, "rewrite_links": true}
+
+// mathValues are the accepted settings of math.
+var mathValues = []string{"true", "false", mkdown.MathKaTeX, mkdown.MathMathML}
+
+// cssModes are the accepted settings of css_mode: whether the css file is
+// appended after the theme stylesheet or replaces it.
+var cssModes = []string{"append", "replace"}
+
+// pathKeys are settings holding paths, which are resolved relative to the
+// config file that sets them.
+var pathKeys = map[string]bool{"include_root": true, "output_dir": true, "css": true, "template": true}
+
+// converterFlags maps the command-line flags shared by conversion and serve
+// to the setting they override.
+var converterFlags = map[string]string{
+	"-t":                "theme",
+	"--theme":           "theme",
+	"--mermaid":         "mermaid",
+	"--math":            "math",
+	"--copy-buttons":    "copy_buttons",
+	"--offline":         "offline",
+	"--self-contained":  "offline",
+	"--inline-images":   "inline_images",
+	"--inline-css":      "inline_css",
+	"--inline-max-size": "inline_max_size",
+	"--toc":             "toc",
+	"--toc-min-depth":   "toc_min_depth",
+	"--toc-max-depth":   "toc_max_depth",
+	"--includes":        "includes",
+	"--include-root":    "include_root",
+	"--wikilinks":       "wikilinks",
+	"--rewrite-links":   "rewrite_links",
+	"--highlight-style": "highlight_style",
+	"--css":             "css",
+	"--css-mode":        "css_mode",
+	"--template":        "template",
+}
+
+// configValue is a resolved setting together with where it came from.
+type configValue struct {
+	value  string
+	source string
+}
+
+// config maps setting keys to their resolved values.
+type config map[string]configValue
+
+func defaultConfig() config {
+	return config{
+		"theme":   {value: "dark", source: "default"},
+		"mermaid": {value: "false", source: "default"},
+		"math":    {value: "false", source: "default"},
+		"offline": {value: "false", source: "default"},
+
+		"copy_buttons":  {value: "false", source: "default"},
+		"inline_images": {value: "false", source: "default"},
+		"inline_css":    {value: "false", source: "default"},
+		"toc":           {value: "false", source: "default"},
+		"includes":      {value: "false", source: "default"},
+		"wikilinks":     {value: "false", source: "default"},
+		"rewrite_links": {value: "true", source: "default"},
+		"css_mode":      {value: "append", source: "default"},
+	}
+}
+
+func (c config) get(key string) string {
+	return c[key].value
+}
+
+func (c config) enabled(key string) bool {
+	v := c[key].value
+	return v != "" && v != "false"
+}
+
+// mathOutput returns the math output format, which is KaTeX unless one is
+// named.
+func (c config) mathOutput() string {
+	if c.get("math") == mkdown.MathMathML {
+		return mkdown.MathMathML
+	}
+	return mkdown.MathKaTeX
+}
+
+// converterOptions maps the settings onto the converter's options.
+func (c config) converterOptions() mkdown.ConverterOptions {
+	// Values are checked by validate.
+	maxSize, _ := parseSize(c.get("inline_max_size"))
+	minDepth, _ := strconv.Atoi(c.get("toc_min_depth"))
+	maxDepth, _ := strconv.Atoi(c.get("toc_max_depth"))
+
+	// Themes from a themes directory replace the built-in stylesheet, as
+	// does --css in replace mode.
+	theme, themeCSS, css := c.get("theme"), c.get("theme_css"), c.get("css")
+	if appearance := c.get("theme_appearance"); appearance != "" {
+		theme = appearance
+	}
+	if css != "" && c.get("css_mode") == "replace" {
+		themeCSS, css = css, ""
+	}
+
+	return mkdown.ConverterOptions{
+		Theme:          theme,
+		ThemeCSSPath:   themeCSS,
+		EnableMermaid:  c.enabled("mermaid"),
+		EnableMath:     c.enabled("math"),
+		MathOutput:     c.mathOutput(),
+		CopyButtons:    c.enabled("copy_buttons"),
+		HighlightStyle: c.get("highlight_style"),
+		CSSPath:        css,
+		TemplatePath:   c.get("template"),
+		Offline:        c.enabled("offline"),
+		InlineImages:   c.enabled("inline_images"),
+		InlineCSS:      c.enabled("inline_css"),
+		MaxInlineSize:  maxSize,
+		TOC:            c.enabled("toc"),
+		TOCMinDepth:    minDepth,
+		TOCMaxDepth:    maxDepth,
+		Includes:       c.enabled("includes"),
+		IncludeRoot:    c.get("include_root"),
+		WikiLinks:      c.enabled("wikilinks"),
+		RewriteLinks:   c.enabled("rewrite_links"),
+		WarningHandler: printWarning,
+	}
+}
+
+// printWarning reports a conversion warning on stderr.
+func printWarning(path, message string) {
+	if path == "" {
+		path = "<stdin>"
+	}
+	fmt.Fprintf(os.Stderr, "⚠ Warning: %s: %s\n", path, message)
+}
+
+// parseConverterFlag records the converter flag at args[i] in flags and
+// returns the index of the last argument it consumed. It reports false when
+// args[i] is not a converter flag. Values may also be given as --flag=value,
+// and boolean flags turned off with --no-flag.
+func parseConverterFlag(args []string, i int, flags config) (int, bool) {
+	arg, value, hasValue := strings.Cut(args[i], "=")
+	key, ok := converterFlags[arg]
+	if !ok {
+		// --no-<flag> turns a boolean setting off.
+		name, negated := strings.CutPrefix(arg, "--no-")
+		key, ok = converterFlags["--"+name]
+		if !negated || !ok || !boolKeys[key] || hasValue {
+			return i, false
+		}
+		flags[key] = configValue{value: "false", source: "flag " + arg}
+		return i, true
+	}
+
+	source := "flag " + arg
+	if hasValue {
+		flags[key] = configValue{value: value, source: source}
+		return i, true
+	}
+	if boolKeys[key] {
+		flags[key] = configValue{value: "true", source: source}
+		return i, true
+	}
+
+	if i+1 >= len(args) {
+		fmt.Fprintf(os.Stderr, "Error: %s requires an argument\n", arg)
+		os.Exit(1)
+	}
+	flags[key] = configValue{value: args[i+1], source: source}
+	return i + 1, true
+}
+
+// loadConfig merges, in increasing order of precedence, the defaults, the
+// global ~/.mkdown.yml, the nearest .mkdown.yml found by walking up from
+// startDir, and the settings given as command-line flags.
+func loadConfig(startDir string, flags config) (config, error) {
+	cfg := defaultConfig()
+
+	var files []string
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, configFileName))
+	}
+	if project := findProjectConfig(startDir); project != "" && (len(files) == 0 || project != files[0]) {
+		files = append(files, project)
+	}
+
+	for _, path := range files {
+		if err := cfg.mergeFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, value := range flags {
+		cfg[key] = value
+	}
+
+	if err := cfg.resolveTheme(startDir); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.validate()
+}
+
+// resolveTheme looks up the configured theme among the built-in and
+// discovered themes. For a discovered theme it records the stylesheet and
+// appearance under the internal theme_css and theme_appearance keys, and
+// its highlight style unless one is already set.
+func (c config) resolveTheme(startDir string) error {
+	themes, err := discoverThemes(startDir)
+	if err != nil {
+		return err
+	}
+
+	v := c["theme"]
+	t, ok := findTheme(themes, v.value)
+	if !ok {
+		return fmt.Errorf("invalid theme '%s' (from %s). Available: %s", v.value, v.source, strings.Join(themeNames(themes), ", "))
+	}
+	if t.stylesheet == "" {
+		return nil // built-in
+	}
+
+	c["theme_css"] = configValue{value: t.stylesheet, source: v.source}
+	c["theme_appearance"] = configValue{value: t.appearance, source: v.source}
+	if t.highlightStyle != "" && c.get("highlight_style") == "" {
+		c["highlight_style"] = configValue{value: t.highlightStyle, source: t.manifest}
+	}
+	return nil
+}
+
+// findProjectConfig returns the .mkdown.yml closest to dir, searching dir and
+// then each of its parents, or "" when there is none.
+func findProjectConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(dir, configFileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mergeFile overlays the settings in the YAML file at path. A missing file is
+// not an error.
+func (c config) mergeFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil // empty file
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s:%d: expected a mapping of settings", path, root.Line)
+	}
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		keyNode, valueNode := root.Content[i], root.Content[i+1]
+		key := keyNode.Value
+
+		if !isConfigKey(key) {
+			return fmt.Errorf("%s:%d: unknown key '%s' (valid keys: %s)", path, keyNode.Line, key, strings.Join(configKeys, ", "))
+		}
+		if valueNode.Kind != yaml.ScalarNode {
+			return fmt.Errorf("%s:%d: '%s' must be a single value", path, valueNode.Line, key)
+		}
+
+		value := valueNode.Value
+		switch {
+		case key == "math" && (value == mkdown.MathKaTeX || value == mkdown.MathMathML):
+		case boolKeys[key]:
+			var enabled bool
+			if err := valueNode.Decode(&enabled); err != nil {
+				return fmt.Errorf("%s:%d: '%s' must be true or false, got '%s'", path, valueNode.Line, key, value)
+			}
+			value = fmt.Sprint(enabled)
+		case pathKeys[key]:
+			value = resolveConfigPath(filepath.Dir(path), value)
+		}
+
+		c[key] = configValue{value: value, source: path}
+	}
+
+	return nil
+}
+
+// resolveConfigPath expands a leading ~ and makes relative paths relative to
+// the directory of the config file.
+func resolveConfigPath(dir, path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func isConfigKey(key string) bool {
+	return slices.Contains(configKeys, key)
+}
+
+// validate checks the merged settings, naming the source of any bad value.
+func (c config) validate() error {
+	if math := c["math"]; !slices.Contains(mathValues, math.value) {
+		return fmt.Errorf("invalid math setting '%s' (from %s). Available: %s", math.value, math.source, strings.Join(mathValues, ", "))
+	}
+	for _, key := range []string{"mermaid", "copy_buttons", "offline", "inline_images", "inline_css", "toc", "includes", "wikilinks", "rewrite_links"} {
+		if v := c[key]; v.value != "true" && v.value != "false" {
+			return fmt.Errorf("invalid %s setting '%s' (from %s). Available: true, false", key, v.value, v.source)
+		}
+	}
+
+	if size := c["inline_max_size"]; size.value != "" {
+		if _, err := parseSize(size.value); err != nil {
+			return fmt.Errorf("invalid inline_max_size '%s' (from %s): use a size such as 500KB or 2MB", size.value, size.source)
+		}
+	}
+
+	depths := map[string]int{}
+	for _, key := range []string{"toc_min_depth", "toc_max_depth"} {
+		v := c[key]
+		if v.value == "" {
+			continue
+		}
+		depth, err := strconv.Atoi(v.value)
+		if err != nil || depth < 1 || depth > 6 {
+			return fmt.Errorf("invalid %s '%s' (from %s): use a heading level from 1 to 6", key, v.value, v.source)
+		}
+		depths[key] = depth
+	}
+	if min, max := depths["toc_min_depth"], depths["toc_max_depth"]; min != 0 && max != 0 && min > max {
+		return fmt.Errorf("toc_min_depth (%d, from %s) is greater than toc_max_depth (%d, from %s)", min, c["toc_min_depth"].source, max, c["toc_max_depth"].source)
+	}
+
+	if style := c["highlight_style"]; style.value != "" {
+		if _, ok := styles.Registry[style.value]; !ok {
+			return fmt.Errorf("unknown highlight style '%s' (from %s)", style.value, style.source)
+		}
+	}
+
+	if mode := c["css_mode"]; !slices.Contains(cssModes, mode.value) {
+		return fmt.Errorf("invalid css_mode '%s' (from %s). Available: %s", mode.value, mode.source, strings.Join(cssModes, ", "))
+	}
+
+	for _, key := range []string{"css", "template"} {
+		if v := c[key]; v.value != "" {
+			if _, err := os.Stat(v.value); err != nil {
+				return fmt.Errorf("%s file '%s' (from %s) not found", key, v.value, v.source)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sizeUnits are the suffixes accepted by parseSize, longest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+// parseSize parses a byte count such as "512", "500KB" or "1.5MB". Units are
+// powers of 1024 and case-insensitive. An empty string is zero.
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, multiplier = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.bytes
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size")
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// runConfig implements "mkdown config show [path] [flags]", which prints the
+// effective settings for path and where each one came from.
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprintln(os.Stderr, "Usage: mkdown config show [path] [flags]")
+		os.Exit(1)
+	}
+
+	dir := "."
+	flags := config{}
+	for i := 1; i < len(args); i++ {
+		if next, ok := parseConverterFlag(args, i, flags); ok {
+			i = next
+			continue
+		}
+		if strings.HasPrefix(args[i], "-") {
+			fmt.Fprintf(os.Stderr, "Error: Unknown flag: %s\n", args[i])
+			os.Exit(1)
+		}
+		dir = configStartDir(args[i])
+	}
+
+	cfg, err := loadConfig(dir, flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, key := range configKeys {
+		v, ok := cfg[key]
+		if !ok {
+			fmt.Fprintf(w, "%s\t(unset)\t\n", key)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, v.value, v.source)
+	}
+	w.Flush()
+}
+
+// configStartDir returns the directory to search for a project config from
+// when converting inputs.
+func configStartDir(input string) string {
+	if input == stdioPath {
+		return "."
+	}
+	if info, err := os.Stat(input); err == nil && info.IsDir() {
+		return input
+	}
+	if hasGlobMeta(input) {
+		root, _ := splitGlob(input)
+		return root
+	}
+	return filepath.Dir(input)
+}
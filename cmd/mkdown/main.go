@@ -1,28 +1,54 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/ekinertac/mkdown/internal"
+	"github.com/ekinertac/mkdown/mkdown"
 )
 
 const version = "0.1.0"
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "config":
+			runConfig(os.Args[2:])
+			return
+		case "themes":
+			runThemes(os.Args[2:])
+			return
+		case "styles":
+			runStyles(os.Args[2:])
+			return
+		}
+	}
+
 	// Parse flags manually to allow flags after positional args
 	var (
-		showVersion   bool
-		outputPath    string
-		inputPath     string
-		theme         = "dark" // default theme
-		enableMermaid bool
-		enableMath    bool
+		showVersion bool
+		outputPath  string
+		inputs      []string
+		watchMode   bool
+		flags       = config{}
 	)
 
 	for i := 1; i < len(os.Args); i++ {
+		if next, ok := parseConverterFlag(os.Args, i, flags); ok {
+			i = next
+			continue
+		}
+
 		arg := os.Args[i]
 		switch arg {
 		case "-v", "--version":
@@ -35,46 +61,76 @@ func main() {
 				fmt.Fprintln(os.Stderr, "Error: -o requires an argument")
 				os.Exit(1)
 			}
-		case "-t", "--theme":
-			if i+1 < len(os.Args) {
-				theme = os.Args[i+1]
-				if theme != "dark" && theme != "light" {
-					fmt.Fprintf(os.Stderr, "Error: Invalid theme '%s'. Available: dark, light\n", theme)
-					os.Exit(1)
-				}
-				i++ // Skip next arg
-			} else {
-				fmt.Fprintln(os.Stderr, "Error: -t requires an argument")
-				os.Exit(1)
-			}
-		case "--mermaid":
-			enableMermaid = true
-		case "--math":
-			enableMath = true
+		case "-w", "--watch":
+			watchMode = true
 		case "-h", "--help":
-			fmt.Println("Usage: mkdown <input.md> [flags]")
+			fmt.Println("Usage: mkdown <input.md | dir | glob | ->... [flags]")
+			fmt.Println("       mkdown serve [dir | file.md] [flags]")
+			fmt.Println("       mkdown check [input.md | dir | glob]... [--external]")
+			fmt.Println("       mkdown config show [path] [flags]")
+			fmt.Println("       mkdown themes list [path]")
+			fmt.Println("       mkdown styles [--preview | --plain]")
 			fmt.Println("\nFlags:")
 			fmt.Println("  -o, --output <path>  Output file path (default: input file name with .html extension)")
-			fmt.Println("  -t, --theme <name>   Theme to use: dark (default), light")
+			fmt.Println("                       When converting a directory, glob or several files, the")
+			fmt.Println("                       output directory to mirror the input structure into.")
+			fmt.Println("                       Use - to write to standard output")
+			fmt.Println("  -t, --theme <name>   Theme to use: dark (default), light, auto, or an installed theme")
+			fmt.Println("                       (see 'mkdown themes list')")
 			fmt.Println("  --mermaid            Enable Mermaid diagram support (requires internet)")
 			fmt.Println("  --math               Enable math rendering with KaTeX (requires internet)")
+			fmt.Println("  --math=mathml        Render math to MathML at build time (no JavaScript)")
+			fmt.Println("  --copy-buttons       Add copy buttons and language labels to code blocks")
+			fmt.Println("  --offline            Inline Mermaid and KaTeX so pages work without internet")
+			fmt.Println("                       (alias: --self-contained)")
+			fmt.Println("  --inline-images      Embed local images as data URIs")
+			fmt.Println("  --inline-css         Embed linked local stylesheets")
+			fmt.Println("  --inline-max-size <size>")
+			fmt.Println("                       Largest file to embed (default: 5MB)")
+			fmt.Println("  --toc                Add a table of contents sidebar")
+			fmt.Println("  --toc-min-depth <n>  Shallowest heading level in the table of contents (default: 2)")
+			fmt.Println("  --toc-max-depth <n>  Deepest heading level in the table of contents (default: 3)")
+			fmt.Println("  --includes           Splice in files named by !include directives and code")
+			fmt.Println("                       block file= attributes")
+			fmt.Println("  --include-root <dir> Directory included files must be inside (default: the")
+			fmt.Println("                       inputs' directory)")
+			fmt.Println("  --wikilinks          Turn [[Page Name]] into links to generated pages")
+			fmt.Println("  --no-rewrite-links   Keep links to .md files instead of pointing them at the")
+			fmt.Println("                       generated .html pages")
+			fmt.Println("  --highlight-style <name>")
+			fmt.Println("                       Chroma style for syntax highlighting (default: monokai for")
+			fmt.Println("                       dark, github for light; see 'mkdown styles')")
+			fmt.Println("  --css <path>         Stylesheet appended after the theme")
+			fmt.Println("  --css-mode <mode>    append (default) or replace the theme stylesheet with --css")
+			fmt.Println("  --template <path>    Custom html/template file to render pages with")
+			fmt.Println("  -w, --watch          Keep running and regenerate HTML when sources change")
 			fmt.Println("  -v, --version        Show version")
 			fmt.Println("  -h, --help          Show this help")
 			fmt.Println("\nExamples:")
 			fmt.Println("  mkdown README.md")
 			fmt.Println("  mkdown input.md -o output.html")
 			fmt.Println("  mkdown doc.md --theme light")
+			fmt.Println("  mkdown doc.md --theme auto")
 			fmt.Println("  mkdown diagram.md --mermaid")
 			fmt.Println("  mkdown math.md --math")
+			fmt.Println("  mkdown math.md --math=mathml")
+			fmt.Println("  mkdown doc.md --mermaid --math --offline")
 			fmt.Println("  mkdown doc.md --mermaid --math --theme light")
+			fmt.Println("  mkdown doc.md --css brand.css --css-mode replace")
+			fmt.Println("  mkdown docs/ -o site/")
+			fmt.Println("  mkdown 'notes/**/*.md'")
+			fmt.Println("  mkdown docs/ -o site/ --watch")
+			fmt.Println("  mkdown serve docs/ --port 8080")
+			fmt.Println("  cat doc.md | mkdown - > doc.html")
+			fmt.Println("\nSettings are read from ~/.mkdown.yml and the nearest .mkdown.yml above the")
+			fmt.Println("input, with flags taking precedence. Run 'mkdown config show' to inspect them.")
 			os.Exit(0)
 		default:
-			if !strings.HasPrefix(arg, "-") && inputPath == "" {
-				inputPath = arg
-			} else if strings.HasPrefix(arg, "-") {
+			if strings.HasPrefix(arg, "-") && arg != stdioPath {
 				fmt.Fprintf(os.Stderr, "Error: Unknown flag: %s\n", arg)
 				os.Exit(1)
 			}
+			inputs = append(inputs, arg)
 		}
 	}
 
@@ -83,20 +139,95 @@ func main() {
 		os.Exit(0)
 	}
 
-	if inputPath == "" {
+	if len(inputs) == 0 {
 		fmt.Fprintln(os.Stderr, "Usage: mkdown <input.md> [-o output.html]")
 		fmt.Fprintln(os.Stderr, "Example: mkdown README.md")
 		os.Exit(1)
 	}
 
+	for _, input := range inputs[1:] {
+		if input == stdioPath {
+			fmt.Fprintln(os.Stderr, "Error: '-' can only be used with a single input file")
+			os.Exit(1)
+		}
+	}
+
+	cfg, err := loadConfig(configStartDir(inputs[0]), flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	theme := cfg.get("theme")
+	opts := cfg.converterOptions()
+	outputs := outputIndex{}
+	opts.OutputPath = outputs.lookup
+	if inputs[0] != stdioPath {
+		opts.WikiLinkRoot = inputRoot(inputs)
+		if opts.IncludeRoot == "" {
+			opts.IncludeRoot = opts.WikiLinkRoot
+		}
+	}
+	converter := mkdown.NewConverterWithOptions(opts)
+
+	if inputs[0] == stdioPath || outputPath == stdioPath {
+		if len(inputs) > 1 || isBatchInput(inputs) {
+			fmt.Fprintln(os.Stderr, "Error: '-' can only be used with a single input file")
+			os.Exit(1)
+		}
+		if watchMode {
+			fmt.Fprintln(os.Stderr, "Error: --watch cannot be used with standard input or output")
+			os.Exit(1)
+		}
+		if err := convertStdio(converter, inputs[0], outputPath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if isBatchInput(inputs) {
+		if outputPath == "" {
+			outputPath = cfg.get("output_dir")
+		}
+
+		jobs, err := collectJobs(inputs, outputPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		outputs.set(jobs)
+
+		failed := runBatch(converter, jobs)
+		fmt.Printf("\nConverted %d of %d files (theme: %s%s)\n", len(jobs)-failed, len(jobs), theme, featureSummary(cfg))
+		if failed > 0 {
+			fmt.Fprintf(os.Stderr, "Error: %d of %d files failed\n", failed, len(jobs))
+		}
+
+		if watchMode {
+			watch(converter, func() ([]batchJob, error) {
+				jobs, err := collectJobs(inputs, outputPath)
+				if err == nil {
+					outputs.set(jobs)
+				}
+				return jobs, err
+			})
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	inputPath := inputs[0]
+
 	// Validate input file exists and is markdown
 	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Error: File '%s' not found\n", inputPath)
 		os.Exit(1)
 	}
 
-	if !strings.HasSuffix(strings.ToLower(inputPath), ".md") && 
-	   !strings.HasSuffix(strings.ToLower(inputPath), ".markdown") {
+	if !isMarkdownFile(inputPath) {
 		fmt.Fprintf(os.Stderr, "Error: Input file must be a markdown file (.md or .markdown)\n")
 		os.Exit(1)
 	}
@@ -105,32 +236,104 @@ func main() {
 	if outputPath == "" {
 		ext := filepath.Ext(inputPath)
 		outputPath = strings.TrimSuffix(inputPath, ext) + ".html"
+		if outputDir := cfg.get("output_dir"); outputDir != "" {
+			outputPath = filepath.Join(outputDir, filepath.Base(outputPath))
+		}
 	}
 
 	// Convert
-	converter := internal.NewConverterWithOptions(internal.ConverterOptions{
-		Theme:         theme,
-		EnableMermaid: enableMermaid,
-		EnableMath:    enableMath,
-	})
+	outputs.set([]batchJob{{input: inputPath, output: outputPath}})
 	if err := converter.Convert(inputPath, outputPath); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if !watchMode {
+			os.Exit(1)
+		}
+	} else {
+		fmt.Printf("✓ Generated: %s (theme: %s%s)\n", outputPath, theme, featureSummary(cfg))
 	}
 
-	var features []string
-	if enableMermaid {
-		features = append(features, "mermaid")
+	if watchMode {
+		job := batchJob{input: inputPath, output: outputPath}
+		watch(converter, func() ([]batchJob, error) {
+			return []batchJob{job}, nil
+		})
 	}
-	if enableMath {
-		features = append(features, "math")
+}
+
+// stdioPath is the input or output path that stands for standard input or
+// standard output.
+const stdioPath = "-"
+
+// convertStdio converts a single document when the input, the output or both
+// are standard streams. Reading from stdin writes to stdout unless an output
+// file is given.
+func convertStdio(converter *mkdown.Converter, inputPath, outputPath string) error {
+	var output bytes.Buffer
+	if inputPath == stdioPath {
+		if err := converter.ConvertReader(context.Background(), os.Stdin, &output); err != nil {
+			return err
+		}
+	} else {
+		if !isMarkdownFile(inputPath) {
+			return fmt.Errorf("input file must be a markdown file (.md or .markdown)")
+		}
+		page, err := converter.RenderFile(inputPath)
+		if err != nil {
+			return err
+		}
+		output.Write(page)
 	}
 
-	featureStr := ""
-	if len(features) > 0 {
-		featureStr = fmt.Sprintf(" [%s]", strings.Join(features, ", "))
+	if outputPath == "" || outputPath == stdioPath {
+		_, err := output.WriteTo(os.Stdout)
+		return err
 	}
 
-	fmt.Printf("✓ Generated: %s (theme: %s%s)\n", outputPath, theme, featureStr)
+	if dir := filepath.Dir(outputPath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(outputPath, output.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ Generated: %s\n", outputPath)
+	return nil
 }
 
+// featureSummary formats the enabled optional features for status output.
+func featureSummary(cfg config) string {
+	var features []string
+	if cfg.enabled("mermaid") {
+		features = append(features, "mermaid")
+	}
+	if cfg.enabled("math") {
+		features = append(features, "math ("+cfg.mathOutput()+")")
+	}
+	if cfg.enabled("copy_buttons") {
+		features = append(features, "copy buttons")
+	}
+	if cfg.enabled("offline") {
+		features = append(features, "offline")
+	}
+	if cfg.enabled("inline_images") {
+		features = append(features, "inline images")
+	}
+	if cfg.enabled("inline_css") {
+		features = append(features, "inline css")
+	}
+	if cfg.enabled("toc") {
+		features = append(features, "toc")
+	}
+	if cfg.enabled("includes") {
+		features = append(features, "includes")
+	}
+	if cfg.enabled("wikilinks") {
+		features = append(features, "wikilinks")
+	}
+
+	if len(features) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" [%s]", strings.Join(features, ", "))
+}
@@ -1,13 +1,57 @@
 package main
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+// binaryDir holds the mkdown binary built for the tests.
+var (
+	binaryDir   string
+	binaryOnce  sync.Once
+	binaryError error
 )
 
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "mkdown-test")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	binaryDir = dir
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// buildBinary builds the mkdown binary the first time it is called and
+// returns its path, so the tests running it share one build.
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	binary := filepath.Join(binaryDir, "mkdown-test")
+	binaryOnce.Do(func() {
+		output, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput()
+		if err != nil {
+			binaryError = fmt.Errorf("%v\n%s", err, output)
+		}
+	})
+	if binaryError != nil {
+		t.Fatalf("failed to build binary: %v", binaryError)
+	}
+	return binary
+}
+
 func TestMainIntegration(t *testing.T) {
 	// Build the binary for testing
 	tmpBinary := filepath.Join(t.TempDir(), "mkdown-test")
@@ -201,3 +245,923 @@ func TestMainFileValidation(t *testing.T) {
 	}
 }
 
+func TestMainBatchConversion(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	docsDir := t.TempDir()
+	files := map[string]string{
+		"index.md":           "# Index",
+		"guide/setup.md":     "# Setup",
+		"guide/deep/faq.md":  "# FAQ",
+		"notes.txt":          "not markdown",
+		".hidden/ignored.md": "# Ignored",
+	}
+	for name, content := range files {
+		path := filepath.Join(docsDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		siteDir := filepath.Join(t.TempDir(), "site")
+		cmd := exec.Command(tmpBinary, docsDir, "-o", siteDir)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+
+		for _, name := range []string{"index.html", "guide/setup.html", "guide/deep/faq.html"} {
+			if _, err := os.Stat(filepath.Join(siteDir, name)); err != nil {
+				t.Errorf("expected %s to be generated: %v", name, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(siteDir, ".hidden")); !os.IsNotExist(err) {
+			t.Error("hidden directories should be skipped")
+		}
+		if !strings.Contains(string(output), "Converted 3 of 3 files") {
+			t.Errorf("summary not printed, got: %s", output)
+		}
+	})
+
+	t.Run("recursive glob", func(t *testing.T) {
+		siteDir := filepath.Join(t.TempDir(), "site")
+		cmd := exec.Command(tmpBinary, filepath.Join(docsDir, "guide", "**", "*.md"), "-o", siteDir)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+
+		for _, name := range []string{"setup.html", "deep/faq.html"} {
+			if _, err := os.Stat(filepath.Join(siteDir, name)); err != nil {
+				t.Errorf("expected %s to be generated: %v", name, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(siteDir, "index.html")); !os.IsNotExist(err) {
+			t.Error("files outside the glob should not be converted")
+		}
+	})
+
+	t.Run("failure count", func(t *testing.T) {
+		siteDir := t.TempDir()
+		// A directory where the output file should be makes that file fail.
+		if err := os.MkdirAll(filepath.Join(siteDir, "index.html"), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command(tmpBinary, docsDir, "-o", siteDir)
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatal("expected non-zero exit status when a file fails")
+		}
+		if !strings.Contains(string(output), "1 of 3 files failed") {
+			t.Errorf("failure count not reported, got: %s", output)
+		}
+		if _, err := os.Stat(filepath.Join(siteDir, "guide", "setup.html")); err != nil {
+			t.Error("remaining files should still be converted after a failure")
+		}
+	})
+}
+
+func TestMatchSegments(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.md", "a.md", true},
+		{"*.md", "dir/a.md", false},
+		{"**/*.md", "a.md", true},
+		{"**/*.md", "dir/sub/a.md", true},
+		{"dir/**/a.md", "dir/a.md", true},
+		{"dir/**/a.md", "other/a.md", false},
+		{"**", "dir/sub/a.md", true},
+	}
+
+	for _, tt := range tests {
+		got := matchSegments(strings.Split(tt.pattern, "/"), strings.Split(tt.path, "/"))
+		if got != tt.want {
+			t.Errorf("matchSegments(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMainWatch(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "watched.md")
+	outputPath := filepath.Join(tmpDir, "watched.html")
+	if err := os.WriteFile(inputPath, []byte("# Before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watcher := exec.Command(tmpBinary, inputPath, "--watch")
+	if err := watcher.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Process.Kill()
+
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if html, err := os.ReadFile(outputPath); err == nil && strings.Contains(string(html), want) {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Fatalf("output never contained %q", want)
+	}
+
+	waitFor("Before")
+
+	// Make sure the new modification time differs on coarse-grained filesystems.
+	time.Sleep(1100 * time.Millisecond)
+	if err := os.WriteFile(inputPath, []byte("# After"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("After")
+}
+
+func TestPreviewServer(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "guide"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "index.md"), []byte("# Served Page"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("plain"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(&previewServer{root: root, converter: mkdown.NewConverter("dark")})
+	defer srv.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("markdown page", func(t *testing.T) {
+		status, body := get("/index.md")
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		if !strings.Contains(body, "Served Page</h1>") {
+			t.Error("markdown was not rendered")
+		}
+		if !strings.Contains(body, reloadEndpoint) {
+			t.Error("live-reload client not injected")
+		}
+	})
+
+	t.Run("html alias", func(t *testing.T) {
+		status, body := get("/index.html")
+		if status != http.StatusOK || !strings.Contains(body, "Served Page</h1>") {
+			t.Errorf("expected index.html to render index.md, got %d", status)
+		}
+	})
+
+	t.Run("directory index", func(t *testing.T) {
+		status, body := get("/")
+		if status != http.StatusOK {
+			t.Fatalf("expected 200, got %d", status)
+		}
+		for _, want := range []string{`href="index.md"`, `href="guide/"`} {
+			if !strings.Contains(body, want) {
+				t.Errorf("index listing missing %s", want)
+			}
+		}
+		if strings.Contains(body, "notes.txt") {
+			t.Error("index listing should only contain markdown files and directories")
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		if status, _ := get("/missing.md"); status != http.StatusNotFound {
+			t.Errorf("expected 404, got %d", status)
+		}
+	})
+
+	t.Run("reload for .markdown page", func(t *testing.T) {
+		notesPath := filepath.Join(root, "notes.markdown")
+		if err := os.WriteFile(notesPath, []byte("# Notes"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if status, body := get("/notes.html"); status != http.StatusOK || !strings.Contains(body, "Notes</h1>") {
+			t.Fatalf("expected notes.html to render notes.markdown, got %d", status)
+		}
+
+		resp, err := http.Get(srv.URL + reloadEndpoint + "?path=/notes.html")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		events := make(chan string, 16)
+		go func() {
+			buf := make([]byte, 256)
+			for {
+				n, err := resp.Body.Read(buf)
+				if n > 0 {
+					events <- string(buf[:n])
+				}
+				if err != nil {
+					close(events)
+					return
+				}
+			}
+		}()
+		<-events // the connected comment
+
+		if err := os.WriteFile(notesPath, []byte("# Notes, edited"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case event := <-events:
+			if !strings.Contains(event, "event: reload") {
+				t.Errorf("expected a reload event, got %q", event)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("no reload event after editing notes.markdown")
+		}
+	})
+}
+
+func TestMainConfig(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	home := t.TempDir()
+	project := t.TempDir()
+	docsDir := filepath.Join(project, "docs")
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	globalConfig := filepath.Join(home, ".mkdown.yml")
+	projectConfig := filepath.Join(project, ".mkdown.yml")
+	inputPath := filepath.Join(docsDir, "doc.md")
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(globalConfig, "theme: light\nmermaid: true\n")
+	writeFile(projectConfig, "math: true\noutput_dir: site\n")
+	writeFile(inputPath, "# Configured")
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(tmpBinary, args...)
+		cmd.Env = append(os.Environ(), "HOME="+home)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	t.Run("show merged settings", func(t *testing.T) {
+		output, err := run("config", "show", inputPath, "--mermaid")
+		if err != nil {
+			t.Fatalf("config show failed: %v\nOutput: %s", err, output)
+		}
+
+		for _, want := range []string{
+			"theme", "light", globalConfig,
+			"flag --mermaid",
+			projectConfig,
+			filepath.Join(project, "site"),
+		} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("conversion uses settings", func(t *testing.T) {
+		output, err := run(inputPath)
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(output, "theme: light") || !strings.Contains(output, "math") {
+			t.Errorf("config settings not applied, got: %s", output)
+		}
+		if _, err := os.Stat(filepath.Join(project, "site", "doc.html")); err != nil {
+			t.Errorf("expected output in configured output_dir: %v", err)
+		}
+	})
+
+	t.Run("flags override config", func(t *testing.T) {
+		output, err := run(inputPath, "--theme", "dark")
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(output, "theme: dark") {
+			t.Errorf("flag did not override config, got: %s", output)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		writeFile(projectConfig, "math: true\nthem: light\n")
+		output, err := run(inputPath)
+		if err == nil {
+			t.Fatal("expected an error for an unknown key")
+		}
+		if !strings.Contains(output, projectConfig+":2: unknown key 'them'") {
+			t.Errorf("expected error naming the file, line and key, got: %s", output)
+		}
+	})
+}
+
+func TestMainStdio(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	t.Run("stdin to stdout", func(t *testing.T) {
+		cmd := exec.Command(tmpBinary, "-", "-o", "-")
+		cmd.Stdin = strings.NewReader("---\ntitle: Piped\n---\n# From stdin")
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nStderr: %s", err, stderr.String())
+		}
+
+		for _, want := range []string{"<title>Piped</title>", "From stdin</h1>"} {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("expected stdout to contain %q", want)
+			}
+		}
+		if strings.Contains(string(output), "✓") {
+			t.Error("status messages must not be written to stdout")
+		}
+	})
+
+	t.Run("file to stdout", func(t *testing.T) {
+		inputPath := filepath.Join(t.TempDir(), "doc.md")
+		if err := os.WriteFile(inputPath, []byte("# From file"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := exec.Command(tmpBinary, inputPath, "-o", "-").Output()
+		if err != nil {
+			t.Fatalf("conversion failed: %v", err)
+		}
+		if !strings.Contains(string(output), "From file</h1>") {
+			t.Error("rendered HTML not written to stdout")
+		}
+	})
+
+	t.Run("stdin with batch input", func(t *testing.T) {
+		if err := exec.Command(tmpBinary, "-", "other.md").Run(); err == nil {
+			t.Error("expected an error when mixing stdin with other inputs")
+		}
+	})
+}
+
+func TestMainMathML(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "math.md")
+	outputPath := filepath.Join(tmpDir, "math.html")
+	if err := os.WriteFile(inputPath, []byte("# Math\n\n$$\n\\sqrt{2} \\mystery\n$$\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("renders MathML and warns", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, inputPath, "--math=mathml", "-o", outputPath).CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(string(output), inputPath+`: unsupported LaTeX in math, shown as errors: \mystery (line 4)`) {
+			t.Errorf("expected a warning listing the unsupported command, got: %s", output)
+		}
+
+		html, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(html), "<msqrt><mn>2</mn></msqrt>") {
+			t.Error("math not converted to MathML")
+		}
+		if strings.Contains(string(html), "katex") {
+			t.Error("MathML output should not load KaTeX")
+		}
+	})
+
+	t.Run("invalid math setting", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, inputPath, "--math=svg").CombinedOutput()
+		if err == nil {
+			t.Fatal("expected an error for an unknown math setting")
+		}
+		if !strings.Contains(string(output), "invalid math setting 'svg'") {
+			t.Errorf("unexpected error output: %s", output)
+		}
+	})
+}
+
+func TestMainOffline(t *testing.T) {
+	if _, err := os.Stat(filepath.Join("..", "..", "mkdown", "assets", "SHA256SUMS")); os.IsNotExist(err) {
+		t.Skip("offline assets are not bundled in this tree: run 'make assets-sums' and commit mkdown/assets")
+	}
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "doc.md")
+	outputPath := filepath.Join(tmpDir, "doc.html")
+	if err := os.WriteFile(inputPath, []byte("# Offline\n\n$$\nx^2\n$$\n\n```mermaid\ngraph TD; A-->B\n```\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, flag := range []string{"--offline", "--self-contained"} {
+		t.Run(flag, func(t *testing.T) {
+			output, err := exec.Command(tmpBinary, inputPath, "--mermaid", "--math", flag, "-o", outputPath).CombinedOutput()
+			if err != nil {
+				t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+			}
+			if !strings.Contains(string(output), "offline") {
+				t.Errorf("expected offline in the status line, got: %s", output)
+			}
+
+			html, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(string(html), "cdn.jsdelivr.net") {
+				t.Error("offline output should not load anything from the network")
+			}
+			if !strings.Contains(string(html), "data:font/woff2;base64,") {
+				t.Error("expected the KaTeX fonts to be inlined")
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+		ok    bool
+	}{
+		{"", 0, true},
+		{"512", 512, true},
+		{"100B", 100, true},
+		{"500KB", 500 << 10, true},
+		{"1.5mb", 3 << 19, true},
+		{"2 MB", 2 << 20, true},
+		{"1GB", 1 << 30, true},
+		{"-1", 0, false},
+		{"3XB", 0, false},
+		{"MB", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseSize(tt.input)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d, ok=%v", tt.input, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestInputRoot(t *testing.T) {
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"docs/a", "docs/b/c"} {
+		if err := os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	docs := filepath.Join(tmpDir, "docs")
+
+	tests := []struct {
+		inputs []string
+		want   string
+	}{
+		{[]string{filepath.Join(docs, "a", "x.md")}, filepath.Join(docs, "a")},
+		{[]string{filepath.Join(docs, "a", "x.md"), filepath.Join(docs, "b", "c", "y.md")}, docs},
+		{[]string{filepath.Join(docs, "b")}, filepath.Join(docs, "b")},
+		{[]string{filepath.Join(docs, "**", "*.md")}, docs},
+		// Inputs sharing only the filesystem root have no common root.
+		{[]string{filepath.Join(docs, "a", "x.md"), string(filepath.Separator) + "elsewhere"}, ""},
+	}
+	for _, tt := range tests {
+		if got := inputRoot(tt.inputs); got != tt.want {
+			t.Errorf("inputRoot(%q) = %q, want %q", tt.inputs, got, tt.want)
+		}
+	}
+}
+
+func TestMainInlineAssets(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	writeFile("pixel.svg", `<svg xmlns="http://www.w3.org/2000/svg" width="1" height="1"></svg>`)
+	writeFile("extra.css", "p { margin: 0; }")
+	inputPath := writeFile("doc.md", "<link rel=\"stylesheet\" href=\"extra.css\">\n\n![pixel](pixel.svg)\n\n![missing](missing.png)\n")
+	outputPath := filepath.Join(t.TempDir(), "moved.html")
+
+	output, err := exec.Command(tmpBinary, inputPath, "--inline-images", "--inline-css", "-o", outputPath).CombinedOutput()
+	if err != nil {
+		t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+	}
+	if !strings.Contains(string(output), inputPath+": image 'missing.png' not inlined: file not found") {
+		t.Errorf("expected a warning for the missing image, got: %s", output)
+	}
+
+	html, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`src="data:image/svg+xml;base64,`, "p { margin: 0; }"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+}
+
+func TestMainTOC(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "doc.md")
+	outputPath := filepath.Join(tmpDir, "doc.html")
+	if err := os.WriteFile(inputPath, []byte("# Guide\n\n## Install\n\n### From source\n\n## Use\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("sidebar", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, inputPath, "--toc", "--toc-max-depth", "2", "-o", outputPath).CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+
+		html, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`<nav class="toc toc-sidebar"`, `<a href="#install">Install</a>`} {
+			if !strings.Contains(string(html), want) {
+				t.Errorf("expected %q in output", want)
+			}
+		}
+		if strings.Contains(string(html), `<a href="#from-source">`) {
+			t.Error("headings deeper than --toc-max-depth should be left out")
+		}
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, inputPath, "--toc-min-depth", "7").CombinedOutput()
+		if err == nil {
+			t.Fatal("expected an error for an invalid depth")
+		}
+		if !strings.Contains(string(output), "invalid toc_min_depth '7'") {
+			t.Errorf("unexpected error output: %s", output)
+		}
+	})
+}
+
+func TestMainIncludes(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"docs/guide/doc.md":   "# Doc\n\n!include ../shared/part.md\n",
+		"docs/shared/part.md": "Shared text.\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inputPath := filepath.Join(tmpDir, "docs", "guide", "doc.md")
+	outputPath := filepath.Join(tmpDir, "doc.html")
+
+	convert := func(args ...string) (string, error) {
+		t.Helper()
+		output, err := exec.Command(tmpBinary, append([]string{inputPath, "-o", outputPath}, args...)...).CombinedOutput()
+		if err != nil {
+			return string(output), err
+		}
+		html, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(html), nil
+	}
+
+	html, err := convert()
+	if err != nil {
+		t.Fatalf("conversion failed: %v\nOutput: %s", err, html)
+	}
+	if strings.Contains(html, "Shared text.") {
+		t.Error("expected includes to be off by default")
+	}
+
+	// The file is outside the input's directory unless the root is wider.
+	output, err := convert("--includes")
+	if err == nil || !strings.Contains(output, "cannot include ../shared/part.md: outside") {
+		t.Errorf("expected an error for a file outside the include root, got %v: %s", err, output)
+	}
+	html, err = convert("--includes", "--include-root", filepath.Join(tmpDir, "docs"))
+	if err != nil {
+		t.Fatalf("conversion failed: %v\nOutput: %s", err, html)
+	}
+	if !strings.Contains(html, "Shared text.") {
+		t.Error("expected the file to be included")
+	}
+}
+
+func TestMainRewriteLinks(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"docs/a.md":   "[b](../guides/b.md#top) [[b]] [self](a.md#x) [readme](../README.md)\n",
+		"guides/b.md": "# Top\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	docs, guides, site := filepath.Join(tmpDir, "docs"), filepath.Join(tmpDir, "guides"), filepath.Join(tmpDir, "site")
+
+	t.Run("batch", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, docs, guides, "-o", site, "--wikilinks").CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		html, err := os.ReadFile(filepath.Join(site, "a.html"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			`<a href="b.html#top">b</a>`,
+			`<a class="wikilink" href="b.html">b</a>`,
+			`<a href="a.html#x">self</a>`,
+			`<a href="../README.html">readme</a>`,
+		} {
+			if !strings.Contains(string(html), want) {
+				t.Errorf("expected %q in output", want)
+			}
+		}
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		outputPath := filepath.Join(site, "home.html")
+		output, err := exec.Command(tmpBinary, filepath.Join(docs, "a.md"), "-o", outputPath).CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		html, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`<a href="../guides/b.html#top">b</a>`, `<a href="home.html#x">self</a>`} {
+			if !strings.Contains(string(html), want) {
+				t.Errorf("expected %q in output", want)
+			}
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, filepath.Join(docs, "a.md"), "--no-rewrite-links", "-o", "-").CombinedOutput()
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(string(output), `<a href="../guides/b.md#top">b</a>`) {
+			t.Errorf("expected links to stay unchanged with --no-rewrite-links:\n%s", output)
+		}
+	})
+}
+
+func TestMainCheck(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gone" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"good.md": "# Good\n\n[bad](bad.md#intro) <" + server.URL + "/ok>\n",
+		"bad.md":  "# Intro\n\n[good](good.md#nope) ![img](missing.png)\n\n[web](" + server.URL + "/gone)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	good, bad := filepath.Join(tmpDir, "good.md"), filepath.Join(tmpDir, "bad.md")
+
+	t.Run("clean", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, "check", good).CombinedOutput()
+		if err != nil {
+			t.Fatalf("check failed: %v\nOutput: %s", err, output)
+		}
+		for _, want := range []string{"External links not checked", good + ":3: " + server.URL + "/ok", "✓ Checked 1 files"} {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+	})
+
+	t.Run("broken", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, "check", tmpDir).CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected check to fail:\n%s", output)
+		}
+		for _, want := range []string{
+			bad + ":3: link good.md#nope: no heading with id 'nope' in good.md",
+			bad + ":3: image missing.png: no such file",
+			"broken links in 1 of 2 files",
+		} {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("expected %q in output:\n%s", want, output)
+			}
+		}
+	})
+
+	t.Run("external", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, "check", good, bad, "--external").CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected check to fail:\n%s", output)
+		}
+		if !strings.Contains(string(output), bad+":5: "+server.URL+"/gone: 404 Not Found") {
+			t.Errorf("expected the missing page to be reported:\n%s", output)
+		}
+		if strings.Contains(string(output), "/ok:") || strings.Contains(string(output), "not checked") {
+			t.Errorf("expected only the failing URL to be reported:\n%s", output)
+		}
+	})
+}
+
+func TestMainThemes(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	home := t.TempDir()
+	project := t.TempDir()
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	userThemes := filepath.Join(home, ".config", "mkdown", "themes")
+	projectThemes := filepath.Join(project, ".mkdown", "themes")
+	writeFile(filepath.Join(userThemes, "light", "theme.css"), "body { color: #101010; }")
+	writeFile(filepath.Join(projectThemes, "sepia", "theme.css"), "body { background: #f4ecd8; }")
+	writeFile(filepath.Join(projectThemes, "sepia", "theme.yml"), "description: Warm paper\nappearance: light\nhighlight_style: monokailight\n")
+	inputPath := filepath.Join(project, "docs", "doc.md")
+	outputPath := filepath.Join(project, "docs", "doc.html")
+	writeFile(inputPath, "# Themed\n\n```go\nx := 1\n```\n")
+
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(tmpBinary, args...)
+		cmd.Env = append(os.Environ(), "HOME="+home, "XDG_CONFIG_HOME=")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	readOutput := func() string {
+		t.Helper()
+		html, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(html)
+	}
+
+	t.Run("list", func(t *testing.T) {
+		output, err := run("themes", "list", inputPath)
+		if err != nil {
+			t.Fatalf("themes list failed: %v\nOutput: %s", err, output)
+		}
+		for _, want := range []string{"dark", "built-in", "sepia", "monokailight", "Warm paper", projectThemes, userThemes + " (overrides built-in)"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("expected output to contain %q, got: %s", want, output)
+			}
+		}
+	})
+
+	t.Run("discovered theme", func(t *testing.T) {
+		output, err := run(inputPath, "--theme", "sepia")
+		if err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		html := readOutput()
+		if !strings.Contains(html, "#f4ecd8") {
+			t.Error("theme stylesheet not used")
+		}
+		if strings.Contains(html, "#0d1117") {
+			t.Error("built-in theme CSS should be replaced")
+		}
+		if !strings.Contains(html, "/* Background */ .bg") {
+			t.Error("theme highlight style not used")
+		}
+	})
+
+	t.Run("auto", func(t *testing.T) {
+		if output, err := run(inputPath, "--theme", "auto"); err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		html := readOutput()
+		for _, want := range []string{"@media (prefers-color-scheme: dark)", `className = 'theme-toggle'`} {
+			if !strings.Contains(html, want) {
+				t.Errorf("expected %q in output", want)
+			}
+		}
+	})
+
+	t.Run("override built-in", func(t *testing.T) {
+		if output, err := run(inputPath, "--theme", "light"); err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(readOutput(), "#101010") {
+			t.Error("user theme should override the built-in light theme")
+		}
+	})
+
+	t.Run("css replace", func(t *testing.T) {
+		cssPath := filepath.Join(project, "brand.css")
+		writeFile(cssPath, "body { color: #abcdef; }")
+		if output, err := run(inputPath, "--css", cssPath, "--css-mode", "replace"); err != nil {
+			t.Fatalf("conversion failed: %v\nOutput: %s", err, output)
+		}
+		html := readOutput()
+		if !strings.Contains(html, "#abcdef") || strings.Contains(html, "#0d1117") {
+			t.Error("--css-mode replace should use the stylesheet instead of the theme")
+		}
+	})
+
+	t.Run("unknown theme", func(t *testing.T) {
+		output, err := run(inputPath, "--theme", "nope")
+		if err == nil {
+			t.Fatal("expected an error for an unknown theme")
+		}
+		if !strings.Contains(output, "Available: auto, dark, light, sepia") {
+			t.Errorf("expected the discovered themes to be listed, got: %s", output)
+		}
+	})
+}
+
+func TestMainStyles(t *testing.T) {
+	tmpBinary := buildBinary(t)
+
+	t.Run("list", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, "styles").CombinedOutput()
+		if err != nil {
+			t.Fatalf("styles failed: %v\nOutput: %s", err, output)
+		}
+		for _, want := range []string{"github-dark", "dark, auto", "light, auto"} {
+			if !strings.Contains(string(output), want) {
+				t.Errorf("expected output to contain %q, got: %s", want, output)
+			}
+		}
+		if strings.Contains(string(output), "\x1b[") {
+			t.Error("output that is not a terminal should not be coloured")
+		}
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		output, err := exec.Command(tmpBinary, "styles", "--preview").CombinedOutput()
+		if err != nil {
+			t.Fatalf("styles failed: %v\nOutput: %s", err, output)
+		}
+		if !strings.Contains(string(output), "\x1b[38;2;") {
+			t.Error("expected coloured previews")
+		}
+	})
+}
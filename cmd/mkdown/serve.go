@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+// reloadEndpoint is the server-sent events endpoint the live-reload client
+// listens on. The prefix keeps it from clashing with files being served.
+const reloadEndpoint = "/__mkdown/events"
+
+// reloadScript is injected into every rendered page. It reloads the page
+// when the server reports that the markdown source (or one of its
+// dependencies) has changed.
+const reloadScript = `<script>
+(function () {
+  var source = new EventSource('` + reloadEndpoint + `?path=' + encodeURIComponent(location.pathname));
+  source.addEventListener('reload', function () { location.reload(); });
+})();
+</script>
+`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Index of {{ .Path }}</title>
+    <style>
+        body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 800px; margin: 40px auto; padding: 0 20px; }
+        li { margin: 4px 0; }
+    </style>
+</head>
+<body>
+    <h1>Index of {{ .Path }}</h1>
+    <ul>
+        {{- if ne .Path "/" }}
+        <li><a href="../">../</a></li>
+        {{- end }}
+        {{- range .Entries }}
+        <li><a href="{{ . }}">{{ . }}</a></li>
+        {{- end }}
+    </ul>
+</body>
+</html>
+`))
+
+// previewServer renders markdown files under root on request.
+type previewServer struct {
+	root      string
+	converter *mkdown.Converter
+}
+
+func runServe(args []string) {
+	var (
+		target = "."
+		host   = "localhost"
+		port   = "8000"
+		flags  = config{}
+	)
+
+	for i := 0; i < len(args); i++ {
+		if next, ok := parseConverterFlag(args, i, flags); ok {
+			i = next
+			continue
+		}
+
+		arg := args[i]
+		switch arg {
+		case "-p", "--port", "--host":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s requires an argument\n", arg)
+				os.Exit(1)
+			}
+			i++
+			if arg == "--host" {
+				host = args[i]
+			} else {
+				port = args[i]
+			}
+		case "-h", "--help":
+			fmt.Println("Usage: mkdown serve [dir | file.md] [flags]")
+			fmt.Println("\nRender markdown on request and reload the browser when files change.")
+			fmt.Println("\nFlags:")
+			fmt.Println("  -p, --port <port>    Port to listen on (default: 8000)")
+			fmt.Println("  --host <host>        Host to bind to (default: localhost)")
+			fmt.Println("  -t, --theme <name>   Theme to use: dark (default), light, auto, or an installed theme")
+			fmt.Println("                       (see 'mkdown themes list')")
+			fmt.Println("  --mermaid            Enable Mermaid diagram support (requires internet)")
+			fmt.Println("  --math               Enable math rendering with KaTeX (requires internet)")
+			fmt.Println("  --math=mathml        Render math to MathML at build time (no JavaScript)")
+			fmt.Println("  --copy-buttons       Add copy buttons and language labels to code blocks")
+			fmt.Println("  --offline            Inline Mermaid and KaTeX so pages work without internet")
+			fmt.Println("                       (alias: --self-contained)")
+			fmt.Println("  --inline-images      Embed local images as data URIs")
+			fmt.Println("  --inline-css         Embed linked local stylesheets")
+			fmt.Println("  --inline-max-size <size>")
+			fmt.Println("                       Largest file to embed (default: 5MB)")
+			fmt.Println("  --toc                Add a table of contents sidebar")
+			fmt.Println("  --toc-min-depth <n>  Shallowest heading level in the table of contents (default: 2)")
+			fmt.Println("  --toc-max-depth <n>  Deepest heading level in the table of contents (default: 3)")
+			fmt.Println("  --includes           Splice in files named by !include directives and code")
+			fmt.Println("                       block file= attributes")
+			fmt.Println("  --include-root <dir> Directory included files must be inside (default: the")
+			fmt.Println("                       served directory)")
+			fmt.Println("  --wikilinks          Turn [[Page Name]] into links to generated pages")
+			fmt.Println("  --no-rewrite-links   Keep links to .md files instead of pointing them at the")
+			fmt.Println("                       generated .html pages")
+			fmt.Println("  --highlight-style <name>")
+			fmt.Println("                       Chroma style for syntax highlighting (default: monokai for")
+			fmt.Println("                       dark, github for light; see 'mkdown styles')")
+			fmt.Println("  --css <path>         Stylesheet appended after the theme")
+			fmt.Println("  --css-mode <mode>    append (default) or replace the theme stylesheet with --css")
+			fmt.Println("  --template <path>    Custom html/template file to render pages with")
+			fmt.Println("  -h, --help           Show this help")
+			os.Exit(0)
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fmt.Fprintf(os.Stderr, "Error: Unknown flag: %s\n", arg)
+				os.Exit(1)
+			}
+			target = arg
+		}
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: File '%s' not found\n", target)
+		os.Exit(1)
+	}
+
+	root, start := target, "/"
+	if !info.IsDir() {
+		root, start = filepath.Dir(target), "/"+filepath.Base(target)
+	}
+
+	cfg, err := loadConfig(root, flags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	opts := cfg.converterOptions()
+	opts.WikiLinkRoot = root
+	if opts.IncludeRoot == "" {
+		opts.IncludeRoot = root
+	}
+	srv := &previewServer{
+		root:      root,
+		converter: mkdown.NewConverterWithOptions(opts),
+	}
+
+	addr := net.JoinHostPort(host, port)
+	fmt.Printf("Serving %s at http://%s%s (Ctrl+C to stop)\n", root, addr, start)
+	if err := http.ListenAndServe(addr, srv); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (s *previewServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == reloadEndpoint {
+		s.serveEvents(w, r)
+		return
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	filePath := s.resolve(urlPath)
+
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) && strings.HasSuffix(filePath, ".html") {
+		// Serve foo.html from foo.md so links to generated pages work.
+		if mdPath, mdInfo, ok := pageSource(filePath); ok {
+			filePath, info, err = mdPath, mdInfo, nil
+		}
+	}
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case info.IsDir():
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		s.serveIndex(w, urlPath, filePath)
+	case isMarkdownFile(filePath):
+		s.serveMarkdown(w, filePath)
+	default:
+		http.ServeFile(w, r, filePath)
+	}
+}
+
+// pageSource returns the markdown file the generated page at htmlPath is
+// served from: foo.md, or failing that foo.markdown, for foo.html.
+func pageSource(htmlPath string) (string, os.FileInfo, bool) {
+	for _, ext := range []string{".md", ".markdown"} {
+		mdPath := strings.TrimSuffix(htmlPath, ".html") + ext
+		if info, err := os.Stat(mdPath); err == nil {
+			return mdPath, info, true
+		}
+	}
+	return "", nil, false
+}
+
+// resolve maps a cleaned URL path to a file below the server root.
+func (s *previewServer) resolve(urlPath string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+urlPath)))
+}
+
+func (s *previewServer) serveMarkdown(w http.ResponseWriter, filePath string) {
+	page, err := s.converter.RenderFile(filePath)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error rendering %s: %v", filePath, err), http.StatusInternalServerError)
+		return
+	}
+
+	// Inject the live-reload client just before </body>.
+	if idx := bytes.LastIndex(page, []byte("</body>")); idx != -1 {
+		page = append(page[:idx:idx], append([]byte(reloadScript), page[idx:]...)...)
+	} else {
+		page = append(page, reloadScript...)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(page)
+}
+
+// serveIndex lists the markdown files and subdirectories of dir.
+func (s *previewServer) serveIndex(w http.ResponseWriter, urlPath, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		switch {
+		case strings.HasPrefix(name, "."):
+		case entry.IsDir():
+			names = append(names, name+"/")
+		case isMarkdownFile(name):
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	indexTemplate.Execute(w, struct {
+		Path    string
+		Entries []string
+	}{urlPath, names})
+}
+
+// serveEvents streams a "reload" event whenever the markdown file named by
+// the path query parameter, or one of its dependencies, changes.
+func (s *previewServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	filePath := s.resolve(r.URL.Query().Get("path"))
+	if !isMarkdownFile(filePath) {
+		if mdPath, _, ok := pageSource(filePath); ok {
+			filePath = mdPath
+		} else {
+			// Reload once the page is created.
+			filePath = strings.TrimSuffix(filePath, ".html") + ".md"
+		}
+	}
+
+	snapshot := func() map[string]fileStamp {
+		deps, err := s.converter.Dependencies(filePath)
+		if err != nil {
+			deps = []string{filePath}
+		}
+		stamps := make(map[string]fileStamp, len(deps))
+		for _, dep := range deps {
+			stamps[dep] = statFile(dep)
+		}
+		return stamps
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	stamps := snapshot()
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			changed := false
+			for dep, stamp := range stamps {
+				if statFile(dep) != stamp {
+					changed = true
+					break
+				}
+			}
+			if !changed {
+				continue
+			}
+
+			// Give editors a moment to finish writing before reloading.
+			time.Sleep(watchDebounce)
+			stamps = snapshot()
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+// stylePreview is the code shown for each style by "mkdown styles".
+const stylePreview = `func greet(name string) error { return fmt.Errorf("hi %s: %d", name, 42) } // done`
+
+// runStyles implements "mkdown styles [--preview | --plain]", which lists
+// the Chroma styles accepted by --highlight-style. Each style is previewed
+// in colour when writing to a terminal.
+func runStyles(args []string) {
+	preview := isTerminal(os.Stdout)
+	for _, arg := range args {
+		switch arg {
+		case "--preview":
+			preview = true
+		case "--plain":
+			preview = false
+		case "-h", "--help":
+			fmt.Println("Usage: mkdown styles [--preview | --plain]")
+			fmt.Println("\nList the syntax highlighting styles for --highlight-style.")
+			fmt.Println("\nFlags:")
+			fmt.Println("  --preview            Show a highlighted sample of each style (default on a terminal)")
+			fmt.Println("  --plain              List the styles without samples")
+			fmt.Println("  -h, --help           Show this help")
+			os.Exit(0)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: Unknown flag: %s\n", arg)
+			os.Exit(1)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "NAME\tAPPEARANCE\tDEFAULT FOR")
+	if preview {
+		fmt.Fprint(w, "\tPREVIEW")
+	}
+	fmt.Fprintln(w)
+
+	for _, name := range styles.Names() {
+		style := styles.Get(name)
+		fmt.Fprintf(w, "%s\t%s\t%s", name, styleAppearance(style), styleDefaultFor(name))
+		if preview {
+			sample, err := highlightPreview(style)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Fprintf(w, "\t%s", sample)
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+}
+
+// styleAppearance reports whether a style has a dark or light background.
+func styleAppearance(style *chroma.Style) string {
+	background := style.Get(chroma.Background).Background
+	if background.IsSet() && background.Brightness() < 0.5 {
+		return "dark"
+	}
+	return "light"
+}
+
+// styleDefaultFor names the built-in themes that use a style by default.
+func styleDefaultFor(name string) string {
+	switch name {
+	case mkdown.DefaultDarkHighlightStyle:
+		return "dark, auto"
+	case mkdown.DefaultLightHighlightStyle:
+		return "light, auto"
+	}
+	return "-"
+}
+
+// highlightPreview returns stylePreview coloured with style for a
+// true-colour terminal.
+func highlightPreview(style *chroma.Style) (string, error) {
+	iterator, err := lexers.Get("go").Tokenise(nil, stylePreview)
+	if err != nil {
+		return "", err
+	}
+
+	var sample strings.Builder
+	if err := formatters.TTY16m.Format(&sample, style, iterator); err != nil {
+		return "", err
+	}
+	preview := strings.ReplaceAll(sample.String(), "\n", "")
+
+	// Show the style's background behind the sample, restoring it after
+	// every reset the formatter writes.
+	const reset = "\x1b[0m"
+	if bg := style.Get(chroma.Background).Background; bg.IsSet() {
+		background := fmt.Sprintf("\x1b[48;2;%d;%d;%dm", bg.Red(), bg.Green(), bg.Blue())
+		preview = background + " " + strings.ReplaceAll(preview, reset, reset+background) + " " + reset
+	}
+	return preview, nil
+}
+
+// isTerminal reports whether f is an interactive terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ekinertac/mkdown/mkdown"
+	"gopkg.in/yaml.v3"
+)
+
+// Files making up a theme directory. theme.css is required; theme.yml is
+// optional.
+const (
+	themeStylesheet = "theme.css"
+	themeManifest   = "theme.yml"
+)
+
+// projectThemesDir is the directory, relative to a project, holding its
+// themes.
+var projectThemesDir = filepath.Join(".mkdown", "themes")
+
+// builtinThemes are the themes compiled into mkdown.
+var builtinThemes = []theme{
+	{name: "dark", appearance: "dark", description: "GitHub-style dark theme", source: "built-in"},
+	{name: "light", appearance: "light", description: "GitHub-style light theme", source: "built-in"},
+	{name: mkdown.ThemeAuto, appearance: mkdown.ThemeAuto, description: "Follows the system light/dark setting, with a toggle", source: "built-in"},
+}
+
+// theme is a built-in theme or one discovered in a themes directory.
+type theme struct {
+	name           string
+	appearance     string // dark, light or auto, used for Mermaid diagrams
+	highlightStyle string // Chroma style, or "" for the default
+	description    string
+
+	stylesheet string // theme.css, or "" for built-in themes
+	manifest   string // theme.yml, or "" when the theme has none
+	source     string // directory the theme was found in, or "built-in"
+	overrides  bool   // replaces the built-in theme of the same name
+}
+
+// themeManifestFile is the format of theme.yml.
+type themeManifestFile struct {
+	Description    string `yaml:"description"`
+	Appearance     string `yaml:"appearance"`
+	HighlightStyle string `yaml:"highlight_style"`
+}
+
+// themeDirs returns the directories searched for themes, in order of
+// precedence: the .mkdown/themes directories in startDir and each of its
+// parents, nearest first, then the user's themes directory.
+func themeDirs(startDir string) []string {
+	var dirs []string
+	if dir, err := filepath.Abs(startDir); err == nil {
+		for {
+			dirs = append(dirs, filepath.Join(dir, projectThemesDir))
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+
+	if config := os.Getenv("XDG_CONFIG_HOME"); config != "" {
+		dirs = append(dirs, filepath.Join(config, "mkdown", "themes"))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".config", "mkdown", "themes"))
+	}
+	return dirs
+}
+
+// discoverThemes returns the built-in themes and those found in the theme
+// directories for startDir, sorted by name. A discovered theme hides
+// built-in themes and themes of the same name in lower-precedence
+// directories.
+func discoverThemes(startDir string) ([]theme, error) {
+	found := map[string]theme{}
+	for _, t := range builtinThemes {
+		found[t.name] = t
+	}
+
+	dirs := themeDirs(startDir)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err != nil {
+			continue // no themes here
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			t, err := loadTheme(filepath.Join(dirs[i], entry.Name()))
+			if errors.Is(err, os.ErrNotExist) {
+				continue // not a theme
+			} else if err != nil {
+				return nil, err
+			}
+			builtin, overrides := findTheme(builtinThemes, t.name)
+			if t.appearance == "" {
+				t.appearance = "dark"
+				if overrides {
+					t.appearance = builtin.appearance
+				}
+			}
+			t.overrides = overrides
+			found[t.name] = t
+		}
+	}
+
+	themes := make([]theme, 0, len(found))
+	for _, t := range found {
+		themes = append(themes, t)
+	}
+	sort.Slice(themes, func(i, j int) bool { return themes[i].name < themes[j].name })
+	return themes, nil
+}
+
+// findTheme returns the theme called name from themes.
+func findTheme(themes []theme, name string) (theme, bool) {
+	for _, t := range themes {
+		if t.name == name {
+			return t, true
+		}
+	}
+	return theme{}, false
+}
+
+// themeNames returns the names of themes.
+func themeNames(themes []theme) []string {
+	names := make([]string, len(themes))
+	for i, t := range themes {
+		names[i] = t.name
+	}
+	return names
+}
+
+// loadTheme reads the theme in dir, leaving its appearance empty when
+// theme.yml does not set one. It returns an error satisfying
+// errors.Is(err, os.ErrNotExist) when dir has no theme.css.
+func loadTheme(dir string) (theme, error) {
+	t := theme{
+		name:       filepath.Base(dir),
+		stylesheet: filepath.Join(dir, themeStylesheet),
+		source:     filepath.Dir(dir),
+	}
+	if _, err := os.Stat(t.stylesheet); err != nil {
+		return t, err
+	}
+
+	path := filepath.Join(dir, themeManifest)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	} else if err != nil {
+		return t, err
+	}
+	t.manifest = path
+
+	var manifest themeManifestFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil && err != io.EOF {
+		return t, fmt.Errorf("%s: %w", path, err)
+	}
+
+	switch manifest.Appearance {
+	case "", "dark", "light", mkdown.ThemeAuto:
+	default:
+		return t, fmt.Errorf("%s: appearance must be dark, light or auto, got '%s'", path, manifest.Appearance)
+	}
+	t.appearance = manifest.Appearance
+	t.description = manifest.Description
+	t.highlightStyle = manifest.HighlightStyle
+	return t, nil
+}
+
+// runThemes implements "mkdown themes list [path]", which prints the
+// built-in themes and those discovered for path.
+func runThemes(args []string) {
+	if len(args) == 0 || args[0] != "list" || len(args) > 2 {
+		fmt.Fprintln(os.Stderr, "Usage: mkdown themes list [path]")
+		os.Exit(1)
+	}
+
+	dir := "."
+	if len(args) == 2 {
+		dir = configStartDir(args[1])
+	}
+
+	themes, err := discoverThemes(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tAPPEARANCE\tHIGHLIGHT\tSOURCE\tDESCRIPTION")
+	for _, t := range themes {
+		highlight := t.highlightStyle
+		if highlight == "" {
+			highlight = "-"
+		}
+		source := t.source
+		if t.overrides {
+			source += " (overrides built-in)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.name, t.appearance, highlight, source, t.description)
+	}
+	w.Flush()
+
+	fmt.Println("\nThemes are directories containing theme.css and an optional theme.yml, found in")
+	fmt.Println(".mkdown/themes above the current directory and in ~/.config/mkdown/themes.")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+// watchInterval is how often watched files are polled for changes.
+const watchInterval = 200 * time.Millisecond
+
+// watchDebounce is how long files must stay unchanged before a rebuild
+// starts, so that an editor writing a file in several steps (truncate, write,
+// rename) triggers a single conversion.
+const watchDebounce = 300 * time.Millisecond
+
+// fileStamp identifies a version of a file. The zero value means the file
+// does not exist.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchedJob is a conversion together with the files it was last built from.
+type watchedJob struct {
+	batchJob
+	deps []string
+}
+
+// watch keeps converting until the process is interrupted. listJobs is
+// called on every poll so that markdown files added to a watched directory
+// are picked up; a job is rebuilt whenever its source or one of the files it
+// depends on changes. Errors are reported without stopping the watcher.
+func watch(converter *mkdown.Converter, listJobs func() ([]batchJob, error)) {
+	jobs := make(map[string]*watchedJob)
+	stamps := make(map[string]fileStamp)
+	pending := make(map[string]bool)
+	var lastChange time.Time
+	var lastErr string
+
+	track := func(wj *watchedJob) {
+		deps, err := converter.Dependencies(wj.input)
+		if err != nil {
+			deps = []string{wj.input}
+		}
+		wj.deps = deps
+		for _, dep := range deps {
+			stamps[dep] = statFile(dep)
+		}
+	}
+
+	initial, err := listJobs()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	for _, job := range initial {
+		wj := &watchedJob{batchJob: job}
+		track(wj)
+		jobs[job.input] = wj
+	}
+
+	fmt.Printf("Watching %d file(s) for changes (Ctrl+C to stop)\n", len(jobs))
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		current, err := listJobs()
+		if err != nil {
+			if err.Error() != lastErr {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				lastErr = err.Error()
+			}
+			continue
+		}
+		lastErr = ""
+
+		next := make(map[string]*watchedJob, len(current))
+		for _, job := range current {
+			wj, ok := jobs[job.input]
+			if !ok {
+				wj = &watchedJob{batchJob: job}
+				pending[job.input] = true
+				lastChange = time.Now()
+			}
+			next[job.input] = wj
+		}
+		jobs = next
+
+		changed := make(map[string]bool)
+		for _, wj := range jobs {
+			for _, dep := range wj.deps {
+				if _, done := changed[dep]; done {
+					continue
+				}
+				stamp := statFile(dep)
+				changed[dep] = stamp != stamps[dep]
+				stamps[dep] = stamp
+			}
+		}
+		for input, wj := range jobs {
+			for _, dep := range wj.deps {
+				if changed[dep] {
+					pending[input] = true
+					lastChange = time.Now()
+					break
+				}
+			}
+		}
+
+		if len(pending) == 0 || time.Since(lastChange) < watchDebounce {
+			continue
+		}
+
+		inputs := make([]string, 0, len(pending))
+		for input := range pending {
+			inputs = append(inputs, input)
+		}
+		sort.Strings(inputs)
+
+		for _, input := range inputs {
+			delete(pending, input)
+			wj, ok := jobs[input]
+			if !ok {
+				continue
+			}
+			if err := converter.Convert(wj.input, wj.output); err != nil {
+				fmt.Fprintf(os.Stderr, "✗ Failed: %s: %v\n", wj.input, err)
+			} else {
+				fmt.Printf("✓ Regenerated: %s (%s)\n", wj.output, time.Now().Format("15:04:05"))
+			}
+			track(wj)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package mkdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindAdmonition is the NodeKind of Admonition nodes.
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+// Admonition is a callout block, written as a GitHub alert such as
+// > [!NOTE] or a MkDocs admonition such as !!! tip. Its children are the
+// blocks inside it.
+type Admonition struct {
+	ast.BaseBlock
+	// AdmonitionType is the lower-case type, such as "note" or "warning".
+	// Types without built-in styles are allowed.
+	AdmonitionType string
+	// Title is shown above the content, or not at all when empty.
+	Title string
+	// Collapsible admonitions render as <details>, closed unless Open.
+	Collapsible bool
+	Open        bool
+}
+
+// Kind implements ast.Node.Kind.
+func (n *Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+// Dump implements ast.Node.Dump.
+func (n *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"AdmonitionType": n.AdmonitionType,
+		"Title":          n.Title,
+		"Collapsible":    boolString(n.Collapsible),
+		"Open":           boolString(n.Open),
+	}, nil)
+}
+
+// admonitionStyle is how a type of admonition is shown.
+type admonitionStyle struct {
+	class string // class name suffix, shared by aliases
+	icon  string
+}
+
+// admonitionStyles are the types with their own colour and icon: GitHub's
+// five alert types and the MkDocs types with their aliases. Other types
+// render like notes with a class of their own.
+var admonitionStyles = map[string]admonitionStyle{
+	"note":      {"note", "ℹ"},
+	"info":      {"info", "ℹ"},
+	"todo":      {"info", "☐"},
+	"tip":       {"tip", "★"},
+	"hint":      {"tip", "★"},
+	"success":   {"success", "✔"},
+	"check":     {"success", "✔"},
+	"done":      {"success", "✔"},
+	"important": {"important", "❢"},
+	"question":  {"question", "?"},
+	"help":      {"question", "?"},
+	"faq":       {"question", "?"},
+	"example":   {"example", "☰"},
+	"abstract":  {"abstract", "☰"},
+	"summary":   {"abstract", "☰"},
+	"tldr":      {"abstract", "☰"},
+	"quote":     {"quote", "❝"},
+	"cite":      {"quote", "❝"},
+	"warning":   {"warning", "⚠︎"},
+	"attention": {"warning", "⚠︎"},
+	"caution":   {"caution", "✖"},
+	"danger":    {"caution", "✖"},
+	"error":     {"caution", "✖"},
+	"failure":   {"caution", "✘"},
+	"fail":      {"caution", "✘"},
+	"missing":   {"caution", "✘"},
+	"bug":       {"caution", "✱"},
+}
+
+// style returns the class and icon of the admonition's type.
+func (n *Admonition) style() admonitionStyle {
+	if style, ok := admonitionStyles[n.AdmonitionType]; ok {
+		return style
+	}
+	return admonitionStyle{class: n.AdmonitionType, icon: admonitionStyles["note"].icon}
+}
+
+// defaultAdmonitionTitle is the title of an admonition that does not give
+// one: its type, capitalised.
+func defaultAdmonitionTitle(typ string) string {
+	return strings.ToUpper(typ[:1]) + typ[1:]
+}
+
+// alertMarker matches the first line of a GitHub alert: [!NOTE], followed
+// by + or - to make it collapsible (open or closed) and an optional title.
+var alertMarker = regexp.MustCompile(`^\[!([A-Za-z][\w-]*)\]([+-]?)(?:[ \t]+(.*?))?[ \t]*$`)
+
+// admonitionHeader matches the line opening a MkDocs admonition: !!! for a
+// plain one, ??? for a closed collapsible one and ???+ for an open one,
+// then the type and an optional quoted title.
+var admonitionHeader = regexp.MustCompile(`^(!!!|\?\?\?\+?)[ \t]+([A-Za-z][\w-]*)(?:[ \t]+"([^"]*)")?[ \t]*$`)
+
+// alertTransformer turns block quotes starting with an alert marker into
+// admonitions.
+type alertTransformer struct{}
+
+func (t *alertTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	var quotes []*ast.Blockquote
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if quote, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, quote)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, quote := range quotes {
+		para, ok := quote.FirstChild().(*ast.Paragraph)
+		if !ok || para.Lines().Len() == 0 {
+			continue
+		}
+		first := para.Lines().At(0)
+		match := alertMarker.FindSubmatch(util.TrimRightSpace(first.Value(source)))
+		if match == nil {
+			continue
+		}
+
+		typ := strings.ToLower(string(match[1]))
+		node := &Admonition{
+			AdmonitionType: typ,
+			Title:          string(match[3]),
+			Collapsible:    len(match[2]) > 0,
+			Open:           string(match[2]) == "+",
+		}
+		if node.Title == "" {
+			node.Title = defaultAdmonitionTitle(typ)
+		}
+
+		// Drop the marker line from the paragraph, and the paragraph if
+		// nothing else is in it.
+		for child := para.FirstChild(); child != nil; {
+			start := inlineStart(child)
+			if start < 0 || start >= first.Stop {
+				break
+			}
+			next := child.NextSibling()
+			para.RemoveChild(para, child)
+			child = next
+		}
+		if para.ChildCount() == 0 {
+			quote.RemoveChild(quote, para)
+		}
+
+		for child := quote.FirstChild(); child != nil; {
+			next := child.NextSibling()
+			node.AppendChild(node, child)
+			child = next
+		}
+		quote.Parent().ReplaceChild(quote.Parent(), quote, node)
+	}
+}
+
+// inlineStart returns the source offset an inline node starts at, or -1 if
+// it holds no text.
+func inlineStart(n ast.Node) int {
+	if t, ok := n.(*ast.Text); ok {
+		return t.Segment.Start
+	}
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if start := inlineStart(child); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
+
+// admonitionParser parses MkDocs admonitions, whose content is indented by
+// four spaces below the header line.
+type admonitionParser struct{}
+
+func (b *admonitionParser) Trigger() []byte {
+	return []byte{'!', '?'}
+}
+
+func (b *admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	match := admonitionHeader.FindSubmatch(util.TrimRightSpace(line[pos:]))
+	if match == nil {
+		return nil, parser.NoChildren
+	}
+
+	typ := strings.ToLower(string(match[2]))
+	node := &Admonition{
+		AdmonitionType: typ,
+		Title:          defaultAdmonitionTitle(typ),
+		Collapsible:    match[1][0] == '?',
+		Open:           string(match[1]) == "???+",
+	}
+	if match[3] != nil {
+		node.Title = string(match[3])
+		if node.Title == "" && node.Collapsible {
+			node.Title = defaultAdmonitionTitle(typ)
+		}
+	}
+
+	advanceLine(reader, line, segment)
+	return node, parser.HasChildren
+}
+
+func (b *admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	if util.IsBlank(line) {
+		advanceLine(reader, line, segment)
+		return parser.Continue | parser.HasChildren
+	}
+
+	indent, _ := util.IndentWidth(line, reader.LineOffset())
+	if indent < 4 {
+		return parser.Close
+	}
+	pos, padding := util.IndentPosition(line, reader.LineOffset(), 4)
+	reader.AdvanceAndSetPadding(pos, padding)
+	return parser.Continue | parser.HasChildren
+}
+
+func (b *admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (b *admonitionParser) CanInterruptParagraph() bool {
+	return false
+}
+
+func (b *admonitionParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// admonitionHTMLRenderer renders admonitions as a div with a title, or as a
+// details element with the title as its summary when collapsible. Aliases
+// such as danger get the class of the type they are styled as too.
+type admonitionHTMLRenderer struct {
+	goldmarkhtml.Config
+}
+
+// SetOption receives the options of the HTML renderer, such as XHTML.
+func (r *admonitionHTMLRenderer) SetOption(name renderer.OptionName, value interface{}) {
+	r.Config.SetOption(name, value)
+}
+
+func (r *admonitionHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAdmonition, r.renderAdmonition)
+}
+
+func (r *admonitionHTMLRenderer) renderAdmonition(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	node := n.(*Admonition)
+	element := "div"
+	if node.Collapsible {
+		element = "details"
+	}
+	if !entering {
+		_, _ = w.WriteString("</" + element + ">\n")
+		return ast.WalkContinue, nil
+	}
+
+	style := node.style()
+	class := "admonition admonition-" + style.class
+	if style.class != node.AdmonitionType {
+		class += " admonition-" + node.AdmonitionType
+	}
+	_, _ = w.WriteString("<" + element + ` class="` + html.EscapeString(class) + `"`)
+	if node.Open {
+		if r.XHTML {
+			_, _ = w.WriteString(` open="open"`)
+		} else {
+			_, _ = w.WriteString(" open")
+		}
+	}
+	_, _ = w.WriteString(">\n")
+
+	if node.Title != "" {
+		titleElement := "p"
+		if node.Collapsible {
+			titleElement = "summary"
+		}
+		_, _ = w.WriteString("<" + titleElement + ` class="admonition-title"><span class="admonition-icon" aria-hidden="true">` +
+			style.icon + "</span>" + html.EscapeString(node.Title) + "</" + titleElement + ">\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+// admonitionExtension adds GitHub alerts and MkDocs admonitions to
+// goldmark.
+type admonitionExtension struct{}
+
+func (e *admonitionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&admonitionParser{}, 750)),
+		parser.WithASTTransformers(util.Prioritized(&alertTransformer{}, 500)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&admonitionHTMLRenderer{Config: goldmarkhtml.NewConfig()}, 500),
+	))
+}
@@ -0,0 +1,58 @@
+package mkdown
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAdmonitions(t *testing.T) {
+	input := "> [!NOTE]\n> Useful **information**.\n\n" +
+		"> [!WARNING] Mind the gap\n> Careful.\n\n" +
+		"> [!TIP]-\n> Hidden.\n\n" +
+		"> Plain quote.\n\n" +
+		"!!! danger \"Really dangerous\"\n    Content with `code`.\n\n    - item\n\n" +
+		"???+ my-type\n    Open.\n\n" +
+		"!!! note \"\"\n    Untitled.\n\n" +
+		"After.\n"
+
+	doc, err := New().RenderDocument(context.Background(), []byte(input))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	content := string(doc.Content)
+
+	for _, want := range []string{
+		"<div class=\"admonition admonition-note\">\n<p class=\"admonition-title\"><span class=\"admonition-icon\" aria-hidden=\"true\">ℹ</span>Note</p>\n<p>Useful <strong>information</strong>.</p>\n</div>",
+		`<span class="admonition-icon" aria-hidden="true">⚠︎</span>Mind the gap</p>`,
+		"<details class=\"admonition admonition-tip\">\n<summary class=\"admonition-title\">",
+		"<blockquote>\n<p>Plain quote.</p>\n</blockquote>",
+		`<div class="admonition admonition-caution admonition-danger">`,
+		"Really dangerous</p>\n<p>Content with <code>code</code>.</p>\n<ul>\n<li>item</li>\n</ul>\n</div>",
+		`<details class="admonition admonition-my-type" open="open">`,
+		"My-type</summary>",
+		"<div class=\"admonition admonition-note\">\n<p>Untitled.</p>\n</div>\n<p>After.</p>",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in output:\n%s", want, content)
+		}
+	}
+	if strings.Contains(content, "[!") {
+		t.Error("expected alert markers to be removed")
+	}
+	if !strings.Contains(string(doc.Styles), ".admonition-warning > .admonition-title") {
+		t.Error("expected admonition styles in the theme")
+	}
+
+	// A header on the last line of the file has no newline to leave.
+	for _, input := range []string{"!!! note", "!!! note\n    Last."} {
+		doc, err := New().RenderDocument(context.Background(), []byte(input))
+		if err != nil {
+			t.Fatalf("RenderDocument failed: %v", err)
+		}
+		content := string(doc.Content)
+		if !strings.HasPrefix(content, `<div class="admonition admonition-note">`) || strings.Contains(content, "<p>e</p>") || strings.Contains(content, "<p>.</p>") {
+			t.Errorf("unexpected output for %q:\n%s", input, content)
+		}
+	}
+}
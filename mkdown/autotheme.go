@@ -0,0 +1,243 @@
+package mkdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ThemeAuto is the theme that follows the reader's light or dark system
+// setting, with a toggle button to override it.
+const ThemeAuto = "auto"
+
+// cssRule is a style rule, or an at-rule block such as @media holding
+// further rules.
+type cssRule struct {
+	prelude string    // selector list or at-rule prelude
+	body    string    // declarations of a style rule
+	rules   []cssRule // rules of an at-rule block
+}
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+
+// parseCSS splits a stylesheet into rules. It handles the plain CSS of the
+// built-in themes, not braces inside strings.
+func parseCSS(css string) []cssRule {
+	css = cssComment.ReplaceAllString(css, "")
+
+	var rules []cssRule
+	for {
+		open := strings.IndexByte(css, '{')
+		if open == -1 {
+			return rules
+		}
+		rule := cssRule{prelude: strings.TrimSpace(css[:open])}
+		css = css[open+1:]
+
+		if !strings.HasPrefix(rule.prelude, "@") {
+			end := strings.IndexByte(css, '}')
+			if end == -1 {
+				end = len(css)
+			}
+			rule.body = strings.TrimSpace(css[:end])
+			rules = append(rules, rule)
+			css = css[min(end+1, len(css)):]
+			continue
+		}
+
+		// Find the brace closing the at-rule block.
+		depth, end := 1, len(css)
+		for i := 0; i < len(css) && depth > 0; i++ {
+			switch css[i] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		rule.rules = parseCSS(css[:end])
+		rules = append(rules, rule)
+		css = css[min(end+1, len(css)):]
+	}
+}
+
+// cssDeclaration is a property and its value.
+type cssDeclaration struct {
+	property, value string
+}
+
+// declarations splits a rule body into its declarations.
+func declarations(body string) []cssDeclaration {
+	var list []cssDeclaration
+	for _, decl := range strings.Split(body, ";") {
+		property, value, ok := strings.Cut(decl, ":")
+		if ok {
+			list = append(list, cssDeclaration{strings.TrimSpace(property), strings.TrimSpace(value)})
+		}
+	}
+	return list
+}
+
+// themePalette collects the values that differ between two themes as
+// custom properties.
+type themePalette struct {
+	names       map[[2]string]string // light and dark value to property name
+	light, dark []cssDeclaration
+}
+
+// variable returns a var() reference to the custom property holding the
+// light and dark values, defining it if needed.
+func (p *themePalette) variable(light, dark string) string {
+	key := [2]string{light, dark}
+	name, ok := p.names[key]
+	if !ok {
+		name = fmt.Sprintf("--mkdown-auto-%d", len(p.names)+1)
+		p.names[key] = name
+		p.light = append(p.light, cssDeclaration{name, light})
+		p.dark = append(p.dark, cssDeclaration{name, dark})
+	}
+	return "var(" + name + ")"
+}
+
+// combineThemes builds the auto theme from a light and a dark stylesheet.
+// Values the themes disagree on become custom properties set to the light
+// value by default, and to the dark value when the system prefers dark or
+// the reader picks dark with the toggle, which sets data-theme on <html>.
+// Rules keep their specificity, and their order as long as both
+// stylesheets list them in the same order.
+func combineThemes(light, dark string) string {
+	palette := &themePalette{names: map[[2]string]string{}}
+	rules := mergeRules(parseCSS(light), parseCSS(dark), palette)
+
+	// color-scheme also switches form controls and scrollbars.
+	lightVars := append([]cssDeclaration{{"color-scheme", "light"}}, palette.light...)
+	darkVars := append([]cssDeclaration{{"color-scheme", "dark"}}, palette.dark...)
+
+	var b strings.Builder
+	writeBlock(&b, ":root", lightVars, "")
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	writeBlock(&b, `:root:not([data-theme="light"])`, darkVars, "  ")
+	b.WriteString("}\n")
+	writeBlock(&b, `:root[data-theme="dark"]`, darkVars, "")
+	writeRules(&b, rules, "")
+	return b.String()
+}
+
+// mergeRules merges the rules of two themes, pairing rules with the same
+// selector or at-rule prelude and replacing the values they disagree on
+// with palette variables. Rules only the dark theme has go last.
+func mergeRules(light, dark []cssRule, palette *themePalette) []cssRule {
+	used := make([]bool, len(dark))
+	var merged []cssRule
+	for i := range light {
+		var match *cssRule
+		for j := range dark {
+			if !used[j] && dark[j].prelude == light[i].prelude && (dark[j].rules == nil) == (light[i].rules == nil) {
+				used[j], match = true, &dark[j]
+				break
+			}
+		}
+		merged = append(merged, mergeRule(&light[i], match, palette))
+	}
+	for j := range dark {
+		if !used[j] {
+			merged = append(merged, mergeRule(nil, &dark[j], palette))
+		}
+	}
+	return merged
+}
+
+// mergeRule merges a rule of the light theme with the same rule of the dark
+// theme. Either may be nil when only one theme has the rule.
+func mergeRule(light, dark *cssRule, palette *themePalette) cssRule {
+	var lightRules, darkRules []cssRule
+	var lightDecls, darkDecls []cssDeclaration
+	rule := light
+	if light != nil {
+		lightRules, lightDecls = light.rules, declarations(light.body)
+	}
+	if dark != nil {
+		darkRules, darkDecls = dark.rules, declarations(dark.body)
+		rule = dark
+	}
+
+	if rule.rules != nil {
+		return cssRule{prelude: rule.prelude, rules: mergeRules(lightRules, darkRules, palette)}
+	}
+	return cssRule{prelude: rule.prelude, body: mergeDeclarations(lightDecls, darkDecls, palette)}
+}
+
+// mergeDeclarations merges the declarations of a rule in both themes. A
+// property only one theme sets gets a palette variable set to "initial" in
+// the other. That makes the var() invalid at computed-value time there, so
+// the property behaves as if set to "unset": it inherits or takes its
+// initial value, rather than the value an earlier rule would have given it
+// had the declaration been absent.
+func mergeDeclarations(light, dark []cssDeclaration, palette *themePalette) string {
+	used := make([]bool, len(dark))
+	var merged []cssDeclaration
+	for _, l := range light {
+		darkValue := "initial"
+		for j, d := range dark {
+			if !used[j] && d.property == l.property {
+				used[j], darkValue = true, d.value
+				break
+			}
+		}
+		merged = append(merged, mergeDeclaration(l.property, l.value, darkValue, palette))
+	}
+	for j, d := range dark {
+		if !used[j] {
+			merged = append(merged, mergeDeclaration(d.property, "initial", d.value, palette))
+		}
+	}
+
+	lines := make([]string, len(merged))
+	for i, decl := range merged {
+		lines[i] = decl.property + ": " + decl.value + ";"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func mergeDeclaration(property, light, dark string, palette *themePalette) cssDeclaration {
+	if light == dark {
+		return cssDeclaration{property, light}
+	}
+	light, lightImportant := strings.CutSuffix(light, "!important")
+	dark, darkImportant := strings.CutSuffix(dark, "!important")
+	value := palette.variable(strings.TrimSpace(light), strings.TrimSpace(dark))
+	if lightImportant || darkImportant {
+		value += " !important"
+	}
+	return cssDeclaration{property, value}
+}
+
+// writeBlock writes a rule setting declarations.
+func writeBlock(b *strings.Builder, selector string, decls []cssDeclaration, indent string) {
+	b.WriteString(indent + selector + " {\n")
+	for _, decl := range decls {
+		b.WriteString(indent + "  " + decl.property + ": " + decl.value + ";\n")
+	}
+	b.WriteString(indent + "}\n")
+}
+
+// writeRules writes rules as CSS, with a blank line before each top-level
+// rule.
+func writeRules(b *strings.Builder, rules []cssRule, indent string) {
+	for _, rule := range rules {
+		if indent == "" {
+			b.WriteString("\n")
+		}
+		b.WriteString(indent + rule.prelude + " {\n")
+		if rule.rules != nil {
+			writeRules(b, rule.rules, indent+"  ")
+		} else {
+			for _, line := range strings.Split(rule.body, "\n") {
+				b.WriteString(indent + "  " + line + "\n")
+			}
+		}
+		b.WriteString(indent + "}\n")
+	}
+}
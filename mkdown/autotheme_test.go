@@ -0,0 +1,73 @@
+package mkdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAutoTheme(t *testing.T) {
+	c := New(WithTheme(ThemeAuto), WithMermaid(true))
+	output, err := c.ConvertBytes([]byte("# Auto\n\n```mermaid\ngraph TD\n```\n"))
+	if err != nil {
+		t.Fatalf("ConvertBytes failed: %v", err)
+	}
+
+	outputStr := string(output)
+	for _, want := range []string{
+		"@media (prefers-color-scheme: dark)",
+		`:root[data-theme="dark"]`,
+		"#0d1117", // dark palette
+		"#ffffff", // light palette
+		"localStorage",
+		"theme: 'auto'",
+	} {
+		if !strings.Contains(outputStr, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Index(outputStr, "mkdown-theme") > strings.Index(outputStr, "mermaid.initialize") {
+		t.Error("theme script should run before the Mermaid loader")
+	}
+}
+
+func TestCombineThemes(t *testing.T) {
+	light := `/* light */
+body { margin: 0; color: #000; }
+.diff { color: #111; background: #eee; }
+@media (max-width: 900px) {
+  .page { border: 1px solid #ccc; display: block; }
+}`
+	dark := `/* dark */
+body { margin: 0; color: #fff; }
+.diff { color: #eee; }
+@media (max-width: 900px) {
+  .page { border: 1px solid #333; display: block; }
+}`
+
+	css := combineThemes(light, dark)
+	for _, want := range []string{
+		":root {\n  color-scheme: light;\n  --mkdown-auto-1: #000;",
+		"@media (prefers-color-scheme: dark) {\n  :root:not([data-theme=\"light\"]) {\n    color-scheme: dark;\n    --mkdown-auto-1: #fff;",
+		":root[data-theme=\"dark\"] {",
+		"body {\n  margin: 0;\n  color: var(--mkdown-auto-1);\n}",
+		"background: var(--mkdown-auto-3);", // light only
+		"--mkdown-auto-3: initial;",
+		"@media (max-width: 900px) {\n  .page {\n    border: var(--mkdown-auto-4);\n    display: block;",
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("expected %q in:\n%s", want, css)
+		}
+	}
+
+	// Rules only one theme has apply only with that theme
+	css = combineThemes("a { color: red; }", "b { color: blue; }")
+	for _, want := range []string{
+		"--mkdown-auto-1: red;\n  --mkdown-auto-2: initial;",
+		"--mkdown-auto-1: initial;\n    --mkdown-auto-2: blue;",
+		"a {\n  color: var(--mkdown-auto-1);\n}\n\nb {\n  color: var(--mkdown-auto-2);\n}",
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("expected %q in:\n%s", want, css)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package mkdown
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// LinkProblem is a broken link, image or footnote reference found by a
+// LinkChecker.
+type LinkProblem struct {
+	// Path is the file the problem is in: the document or a file it
+	// includes.
+	Path    string
+	Line    int
+	Message string
+}
+
+// String formats the problem as path:line: message.
+func (p LinkProblem) String() string {
+	return sourceLocation(p.Path, p.Line) + ": " + p.Message
+}
+
+// ExternalLink is a link or image pointing at a web page, which a
+// LinkChecker lists without fetching.
+type ExternalLink struct {
+	Path string
+	Line int
+	URL  string
+}
+
+// LinkReport is the result of checking a document.
+type LinkReport struct {
+	Problems []LinkProblem
+	External []ExternalLink
+}
+
+// LinkChecker checks the links of markdown files without network access:
+// that relative link targets and images exist, that #fragments match a
+// heading id, that wikilinks name an existing page and that footnote
+// references have a definition. It remembers the headings of the files it
+// parses, so checking many files that link to each other stays fast, and
+// is not safe for concurrent use.
+type LinkChecker struct {
+	converter *Converter
+	anchors   map[string]map[string]bool // absolute path to the ids in the file
+}
+
+// NewLinkChecker returns a LinkChecker parsing documents the way c does.
+func NewLinkChecker(c *Converter) *LinkChecker {
+	return &LinkChecker{converter: c, anchors: make(map[string]map[string]bool)}
+}
+
+// footnoteReference matches a footnote reference left as text because no
+// definition matched it.
+var footnoteReference = regexp.MustCompile(`\[\^([^\]\s]+)\]`)
+
+// htmlAnchor matches the id and name attributes of raw HTML, which links
+// can point at as well as headings.
+var htmlAnchor = regexp.MustCompile(`\s(?:id|name)="([^"]+)"`)
+
+// Check parses the markdown file at path and reports its broken links. An
+// error means the document could not be parsed, for example because a file
+// it includes is missing.
+func (lc *LinkChecker) Check(path string) (*LinkReport, error) {
+	c := lc.converter
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	_, markdownContent := c.parseFrontmatter(src)
+	root, source, sources, err := c.parse(path, src, markdownContent)
+	if err != nil {
+		return nil, err
+	}
+
+	linker, err := c.newPageLinker(path, "")
+	if err != nil {
+		return nil, err
+	}
+	// Missing pages are reported below, with the other problems.
+	if _, err := c.resolveWikiLinks(root, path, linker, sources.where); err != nil {
+		return nil, err
+	}
+	wikiRoot, _ := c.wikiPagesRoot(path)
+
+	anchors := collectAnchors(root, source)
+	if abs, err := filepath.Abs(path); err == nil {
+		lc.anchors[abs] = anchors
+	}
+
+	report := &LinkReport{}
+	problem := func(n ast.Node, format string, args ...interface{}) {
+		file, line := sources.position(nodeOffset(n, source))
+		if file == "" {
+			file = path
+		}
+		report.Problems = append(report.Problems, LinkProblem{Path: file, Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	external := func(n ast.Node, u string) {
+		file, line := sources.position(nodeOffset(n, source))
+		if file == "" {
+			file = path
+		}
+		report.External = append(report.External, ExternalLink{Path: file, Line: line, URL: u})
+	}
+
+	// checkDestination checks the destination of a link or image.
+	checkDestination := func(n ast.Node, kind, dest string) {
+		u, err := url.Parse(dest)
+		switch {
+		case err != nil:
+			problem(n, "%s %s: invalid URL", kind, dest)
+			return
+		case u.Scheme == "http" || u.Scheme == "https":
+			external(n, dest)
+			return
+		case u.Scheme != "" || u.Host != "" || strings.HasPrefix(u.Path, "/"):
+			return
+		case u.Path == "":
+			if u.Fragment != "" && !anchors[u.Fragment] {
+				problem(n, "%s %s: no heading with id '%s' on this page", kind, dest, u.Fragment)
+			}
+			return
+		}
+
+		target, _ := localPath(baseDir(path), dest)
+		file, ok := pageSource(target)
+		if !ok {
+			problem(n, "%s %s: no such file", kind, dest)
+			return
+		}
+		if u.Fragment != "" {
+			if ids := lc.anchorsOf(file); ids != nil && !ids[u.Fragment] {
+				problem(n, "%s %s: no heading with id '%s' in %s", kind, dest, u.Fragment, u.Path)
+			}
+		}
+	}
+
+	err = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.CodeSpan, *ast.CodeBlock, *ast.FencedCodeBlock, *MathInline, *MathBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.Link:
+			checkDestination(node, "link", string(node.Destination))
+		case *ast.Image:
+			checkDestination(node, "image", string(node.Destination))
+		case *ast.AutoLink:
+			if u := string(node.URL(source)); node.AutoLinkType == ast.AutoLinkURL && (strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://")) {
+				external(node, u)
+			}
+		case *WikiLink:
+			switch {
+			case node.Page != "" && node.file == "":
+				problem(node, "wikilink [[%s]]: no page named '%s' in %s", node.Page, node.Page, wikiRoot)
+			case node.Heading == "":
+			case node.Page == "":
+				if !anchors[slugify(node.Heading)] {
+					problem(node, "wikilink [[#%s]]: no heading '%s' on this page", node.Heading, node.Heading)
+				}
+			default:
+				if ids := lc.anchorsOf(node.file); ids != nil && !ids[slugify(node.Heading)] {
+					problem(node, "wikilink [[%s#%s]]: no heading '%s' in %s", node.Page, node.Heading, node.Heading, node.Page)
+				}
+			}
+		}
+
+		for _, label := range unresolvedFootnotes(n, source) {
+			problem(label.node, "footnote [^%s] has no definition", label.name)
+		}
+		return ast.WalkContinue, nil
+	})
+	return report, err
+}
+
+// pageSource returns the file a local link target refers to: the target
+// itself if it exists, or for a generated page.html the markdown file it
+// is generated from.
+func pageSource(target string) (string, bool) {
+	if _, err := os.Stat(target); err == nil {
+		return target, true
+	}
+	if strings.EqualFold(filepath.Ext(target), ".html") {
+		stem := strings.TrimSuffix(target, filepath.Ext(target))
+		for _, ext := range []string{".md", ".markdown"} {
+			if _, err := os.Stat(stem + ext); err == nil {
+				return stem + ext, true
+			}
+		}
+	}
+	return "", false
+}
+
+// anchorsOf returns the ids links can point at in the markdown file at
+// path, or nil when path is not a markdown file that can be parsed.
+func (lc *LinkChecker) anchorsOf(path string) map[string]bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".md" && ext != ".markdown" {
+		return nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	if anchors, ok := lc.anchors[abs]; ok {
+		return anchors
+	}
+
+	var anchors map[string]bool
+	if src, err := os.ReadFile(path); err == nil {
+		_, markdownContent := lc.converter.parseFrontmatter(src)
+		// A file that cannot be parsed is reported when it is checked.
+		if root, source, _, err := lc.converter.parse(path, src, markdownContent); err == nil {
+			anchors = collectAnchors(root, source)
+		}
+	}
+	lc.anchors[abs] = anchors
+	return anchors
+}
+
+// collectAnchors returns the ids of the headings below root and the id and
+// name attributes of its raw HTML.
+func collectAnchors(root ast.Node, source []byte) map[string]bool {
+	anchors := make(map[string]bool)
+	addHTML := func(html []byte) {
+		for _, match := range htmlAnchor.FindAllSubmatch(html, -1) {
+			anchors[string(match[1])] = true
+		}
+	}
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			if id, ok := node.AttributeString("id"); ok {
+				if b, ok := id.([]byte); ok {
+					anchors[string(b)] = true
+				}
+			}
+		case *ast.HTMLBlock:
+			for i := 0; i < node.Lines().Len(); i++ {
+				line := node.Lines().At(i)
+				addHTML(line.Value(source))
+			}
+		case *ast.RawHTML:
+			for i := 0; i < node.Segments.Len(); i++ {
+				segment := node.Segments.At(i)
+				addHTML(segment.Value(source))
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return anchors
+}
+
+// footnoteLabel is a footnote reference without a definition.
+type footnoteLabel struct {
+	node ast.Node
+	name string
+}
+
+// unresolvedFootnotes returns the footnote references in the text directly
+// below n that no definition matched, which the parser leaves as text. The
+// brackets may be split over several text nodes, so runs of adjacent text
+// are searched together.
+func unresolvedFootnotes(n ast.Node, source []byte) []footnoteLabel {
+	var labels []footnoteLabel
+	for child := n.FirstChild(); child != nil; {
+		first, ok := child.(*ast.Text)
+		if !ok {
+			child = child.NextSibling()
+			continue
+		}
+		last := first
+		for next, ok := last.NextSibling().(*ast.Text); ok && next.Segment.Start == last.Segment.Stop; next, ok = last.NextSibling().(*ast.Text) {
+			last = next
+		}
+		for _, match := range footnoteReference.FindAllSubmatch(source[first.Segment.Start:last.Segment.Stop], -1) {
+			labels = append(labels, footnoteLabel{node: first, name: string(match[1])})
+		}
+		child = last.NextSibling()
+	}
+	return labels
+}
+
+// nodeOffset returns a source offset on the line an inline node starts on:
+// that of its first text, or after the text before it, or failing that the
+// start of the block it is in.
+func nodeOffset(n ast.Node, source []byte) int {
+	if link, ok := n.(*WikiLink); ok {
+		return link.offset
+	}
+	if start := inlineStart(n); start >= 0 {
+		return start
+	}
+	if text, ok := n.PreviousSibling().(*ast.Text); ok {
+		offset := text.Segment.Stop
+		if text.SoftLineBreak() || text.HardLineBreak() {
+			if i := bytes.IndexByte(source[offset:], '\n'); i >= 0 {
+				offset += i + 1
+			}
+		}
+		return offset
+	}
+	for p := n; p != nil; p = p.Parent() {
+		if p.Type() == ast.TypeBlock && p.Lines().Len() > 0 {
+			return p.Lines().At(0).Start
+		}
+	}
+	return 0
+}
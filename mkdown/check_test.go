@@ -0,0 +1,81 @@
+package mkdown
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLinkChecker(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"doc.md": "---\ntitle: Doc\n---\n# Doc\n\n## Install Steps\n\n<a id=\"raw\"></a>\n\n" +
+			"[ok](#install-steps) [raw](#raw) [bad anchor](#nope)\n" +
+			"[other](other.md#usage) [other bad](other.md#missing) [page](other.html#usage) [gone](gone.md)\n" +
+			"![logo](logo.png) ![missing](img/missing.png) [dir](sub) [web](https://example.com/x) <https://example.org> [mail](mailto:a@b.c) [site](/abs.md)\n\n" +
+			"[[other]] [[other#Usage]] [[other#Nope]] [[#Doc]] [[Nowhere]]\n\n" +
+			"Text[^1] and[^missing] and `[^code]`.\n\n" +
+			"[^1]: Defined.\n\n" +
+			"!include part.md\n",
+		"part.md":  "See [nothing](nothing.md).\n",
+		"other.md": "# Other\n\n## Usage\n",
+		"logo.png": "png",
+		"sub/x.md": "x\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	docPath := filepath.Join(tmpDir, "doc.md")
+	report, err := NewLinkChecker(New(WithWikiLinks(true), WithIncludes(true))).Check(docPath)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	var problems []string
+	for _, p := range report.Problems {
+		problems = append(problems, strings.TrimPrefix(p.String(), tmpDir+string(filepath.Separator)))
+	}
+	want := []string{
+		"doc.md:10: link #nope: no heading with id 'nope' on this page",
+		"doc.md:11: link other.md#missing: no heading with id 'missing' in other.md",
+		"doc.md:11: link gone.md: no such file",
+		"doc.md:12: image img/missing.png: no such file",
+		"doc.md:14: wikilink [[other#Nope]]: no heading 'Nope' in other",
+		"doc.md:14: wikilink [[Nowhere]]: no page named 'Nowhere' in " + tmpDir,
+		"doc.md:16: footnote [^missing] has no definition",
+		"part.md:1: link nothing.md: no such file",
+	}
+	if !slices.Equal(problems, want) {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(problems, "\n"), strings.Join(want, "\n"))
+	}
+
+	if len(report.External) != 2 || report.External[0].URL != "https://example.com/x" || report.External[1].URL != "https://example.org" || report.External[1].Line != 12 {
+		t.Errorf("unexpected external links: %+v", report.External)
+	}
+
+	if _, err := NewLinkChecker(New()).Check(filepath.Join(tmpDir, "missing.md")); err == nil {
+		t.Error("expected an error for a missing document")
+	}
+
+	// Brackets in math are not footnote references.
+	mathPath := filepath.Join(tmpDir, "math.md")
+	if err := os.WriteFile(mathPath, []byte("Inline $x[^3]$ here.\n\n$$\na[^n]\n$$\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = NewLinkChecker(New(WithMath(true))).Check(mathPath)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Errorf("expected no problems in math, got %v", report.Problems)
+	}
+}
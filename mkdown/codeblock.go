@@ -0,0 +1,207 @@
+package mkdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// lineRangeSeparator splits hl_lines given as a string, such as "1,3-5".
+var lineRangeSeparator = regexp.MustCompile(`[\s,]+`)
+
+// prepareCodeBlocks reads the attributes in the info strings of fenced code
+// blocks, such as ```go {linenos=true hl_lines="3-5" title="main.go"
+// start=10}, and stores them on the nodes in the form the highlighter
+// understands. It returns a warning for each attribute it cannot use; where
+// describes the position of an offset in source for the warning.
+func prepareCodeBlocks(root ast.Node, source []byte, where func(offset int) string) []string {
+	var warnings []string
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		block, ok := n.(*ast.FencedCodeBlock)
+		if !entering || !ok || block.Info == nil {
+			return ast.WalkContinue, nil
+		}
+
+		info := block.Info.Segment.Value(source)
+		start := bytes.IndexByte(info, '{')
+		if start == -1 {
+			return ast.WalkSkipChildren, nil
+		}
+
+		warn := func(format string, args ...interface{}) {
+			message := fmt.Sprintf(format, args...)
+			warnings = append(warnings, fmt.Sprintf("code block (%s): %s", where(block.Info.Segment.Start), message))
+		}
+
+		attrs, ok := parser.ParseAttributes(text.NewReader(info[start:]))
+		if !ok {
+			warn("could not parse attributes %s", info[start:])
+			return ast.WalkSkipChildren, nil
+		}
+
+		for _, attr := range attrs {
+			name := string(attr.Name)
+			switch name {
+			case "linenos":
+				value, ok := lineNumbersValue(attr.Value)
+				if !ok {
+					warn("linenos must be true, false, table or inline")
+					continue
+				}
+				block.SetAttribute(attr.Name, value)
+			case "start", "linenostart":
+				value, ok := attr.Value.(float64)
+				if !ok || value < 0 || value != float64(int(value)) {
+					warn("%s must be a line number", name)
+					continue
+				}
+				block.SetAttributeString("linenostart", value)
+			case "hl_lines":
+				ranges, err := lineRanges(attr.Value, block.Lines().Len())
+				if err != nil {
+					warn("hl_lines: %v", err)
+					continue
+				}
+				block.SetAttribute(attr.Name, ranges)
+			case "title":
+				value, ok := attr.Value.([]byte)
+				if !ok {
+					warn("title must be a string")
+					continue
+				}
+				block.SetAttribute(attr.Name, value)
+			case "file", "lines", "region":
+				// Read by includeSnippets.
+			default:
+				warn("unknown attribute '%s' (valid: linenos, hl_lines, title, start, file, lines, region)", name)
+			}
+		}
+
+		// A first line number only makes sense with line numbers shown.
+		if _, ok := block.AttributeString("linenostart"); ok {
+			if _, ok := block.AttributeString("linenos"); !ok {
+				block.SetAttributeString("linenos", true)
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return warnings
+}
+
+// lineNumbersValue normalises a linenos attribute to a bool, or to "table"
+// or "inline" for the layout of the numbers.
+func lineNumbersValue(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case []byte:
+		switch string(v) {
+		case "true":
+			return true, true
+		case "false":
+			return false, true
+		case "table", "inline":
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// lineRanges parses hl_lines, given as a list such as [1, "3-5"] or a
+// string such as "1,3-5", into the list form the highlighter reads. Lines
+// count from the first line of the block, whatever the start attribute says.
+func lineRanges(value interface{}, lineCount int) ([]interface{}, error) {
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case []byte:
+		for _, item := range lineRangeSeparator.Split(strings.TrimSpace(string(v)), -1) {
+			items = append(items, []byte(item))
+		}
+	case float64:
+		items = []interface{}{v}
+	default:
+		return nil, fmt.Errorf("expected line numbers such as \"1,3-5\"")
+	}
+
+	var ranges []interface{}
+	for _, item := range items {
+		var text string
+		switch v := item.(type) {
+		case float64:
+			text = strconv.FormatFloat(v, 'f', -1, 64)
+		case []byte:
+			text = string(v)
+		}
+
+		from, to, isRange := strings.Cut(text, "-")
+		if !isRange {
+			to = from
+		}
+		first, err1 := strconv.Atoi(from)
+		last, err2 := strconv.Atoi(to)
+		if err1 != nil || err2 != nil || first < 1 || last < first {
+			return nil, fmt.Errorf("invalid line or range '%s'", text)
+		}
+		if last > lineCount {
+			return nil, fmt.Errorf("line %d is past the end of the %d-line block", last, lineCount)
+		}
+		ranges = append(ranges, []byte(fmt.Sprintf("%d-%d", first, last)))
+	}
+	return ranges, nil
+}
+
+// codeBlockWrapper returns the highlighter's wrapper renderer. It wraps code
+// blocks that have a title in a div.code-block with the title as a caption,
+// and with copyButtons every highlighted block, with its language in
+// data-lang for the copy button script. Blocks that are not highlighted,
+// such as Mermaid diagrams, are written as <pre><code class="language-...">.
+func codeBlockWrapper(copyButtons bool) highlighting.WrapperRenderer {
+	return func(w util.BufWriter, ctx highlighting.CodeBlockContext, entering bool) {
+		var title []byte
+		if attrs := ctx.Attributes(); attrs != nil {
+			if value, ok := attrs.GetString("title"); ok {
+				title, _ = value.([]byte)
+			}
+		}
+		wrap := len(title) > 0 || (copyButtons && ctx.Highlighted())
+
+		if entering {
+			if wrap {
+				_, _ = w.WriteString(`<div class="code-block"`)
+				if language, ok := ctx.Language(); ok && copyButtons {
+					_, _ = w.WriteString(` data-lang="` + html.EscapeString(string(language)) + `"`)
+				}
+				_ = w.WriteByte('>')
+			}
+			if len(title) > 0 {
+				_, _ = w.WriteString(`<div class="code-title">` + html.EscapeString(string(title)) + "</div>")
+			}
+			if !ctx.Highlighted() {
+				_, _ = w.WriteString("<pre><code")
+				if language, ok := ctx.Language(); ok {
+					_, _ = w.WriteString(` class="language-` + html.EscapeString(string(language)) + `"`)
+				}
+				_ = w.WriteByte('>')
+			}
+			return
+		}
+
+		if !ctx.Highlighted() {
+			_, _ = w.WriteString("</code></pre>\n")
+		}
+		if wrap {
+			_, _ = w.WriteString("</div>\n")
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package mkdown
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCodeBlockAttributes(t *testing.T) {
+	input := "---\ntitle: Code\n---\n\n" +
+		"```go {title=\"main.go\" start=10 hl_lines=\"2-3\"}\npackage main\n\nfunc main() {}\n```\n\n" +
+		"```go {linenos=true}\nx := 1\n```\n\n" +
+		"```go {hl_lines=\"9\" colour=red}\nx := 1\n```\n\n" +
+		"```mermaid\ngraph TD\n```\n"
+
+	doc, err := New(WithMermaid(true)).RenderDocument(context.Background(), []byte(input))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	content := string(doc.Content)
+
+	for _, want := range []string{
+		`<div class="code-block"><div class="code-title">main.go</div>`,
+		`<span class="ln">10</span>`,
+		`<span class="ln">12</span>`,
+		`<span class="line hl">`,
+		`<pre><code class="language-mermaid">graph TD`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Contains(content, `<span class="ln">13</span>`) {
+		t.Error("expected line numbers to stop at the end of the block")
+	}
+	if strings.Count(content, `class="code-title"`) != 1 {
+		t.Error("expected only the titled block to have a caption")
+	}
+
+	want := []string{
+		"code block (line 15): hl_lines: line 9 is past the end of the 1-line block",
+		"code block (line 15): unknown attribute 'colour' (valid: linenos, hl_lines, title, start, file, lines, region)",
+	}
+	if len(doc.Warnings) != len(want) {
+		t.Fatalf("expected warnings %v, got %v", want, doc.Warnings)
+	}
+	for i := range want {
+		if doc.Warnings[i] != want[i] {
+			t.Errorf("warning %d = %q, want %q", i, doc.Warnings[i], want[i])
+		}
+	}
+}
+
+func TestCopyButtons(t *testing.T) {
+	input := "```bash\n$ go test ./...\n```\n\n```go {title=\"main.go\"}\npackage main\n```\n\n```mermaid\ngraph TD\n```\n"
+
+	doc, err := New(WithCopyButtons(true), WithMermaid(true)).RenderDocument(context.Background(), []byte(input))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	content, scripts := string(doc.Content), string(doc.Scripts)
+
+	for _, want := range []string{
+		`<div class="code-block" data-lang="bash"><pre`,
+		`<div class="code-block" data-lang="go"><div class="code-title">main.go</div>`,
+		`<pre><code class="language-mermaid">graph TD`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Contains(content, `data-lang="mermaid"`) {
+		t.Error("expected no copy button on Mermaid diagrams")
+	}
+	if !strings.Contains(scripts, "code-copy") {
+		t.Error("expected the copy button script")
+	}
+	if strings.Contains(copyScript, "://") {
+		t.Error("expected the copy button script to load nothing from the network")
+	}
+
+	// Off by default, and not added to pages without code.
+	doc, err = New().RenderDocument(context.Background(), []byte(input))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if strings.Contains(string(doc.Content), "data-lang") || strings.Contains(string(doc.Scripts), "code-copy") {
+		t.Error("expected no copy buttons unless enabled")
+	}
+	doc, err = New(WithCopyButtons(true)).RenderDocument(context.Background(), []byte("# Text only"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if doc.Scripts != "" {
+		t.Errorf("expected no scripts for a page without code, got %q", doc.Scripts)
+	}
+}
@@ -0,0 +1,838 @@
+package mkdown
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/default.html
+var defaultTemplate string
+
+//go:embed templates/dark.css
+var darkThemeCSS string
+
+//go:embed templates/light.css
+var lightThemeCSS string
+
+// Converter renders markdown into HTML pages. It can be reused for many
+// documents and is safe for concurrent use by multiple goroutines; create one
+// with New or NewConverterWithOptions.
+type Converter struct {
+	markdown      goldmark.Markdown
+	template      *template.Template
+	theme         string
+	themeCSSPath  string
+	enableMermaid bool
+	enableMath    bool
+	copyButtons   bool
+	mathOutput    string
+	themeStyles   string // theme and highlighting CSS
+	stylesErr     error  // set when the highlight style is unknown
+	cssPath       string
+	templatePath  string
+	includes      bool
+	includeRoot   string
+	wikiLinkRoot  string
+	wikiPages     *wikiPageCache
+	rewriteLinks  bool
+	outputPath    func(path string) string
+	offline       bool
+	inlineImages  bool
+	inlineCSS     bool
+	maxInlineSize int64
+	toc           bool
+	tocMinDepth   int
+	tocMaxDepth   int
+	warn          func(path, message string)
+}
+
+// Math output formats for ConverterOptions.MathOutput.
+const (
+	// MathKaTeX leaves the TeX source in the page and loads KaTeX from a CDN
+	// to typeset it in the browser.
+	MathKaTeX = "katex"
+	// MathMathML converts math to MathML while rendering, which browsers
+	// display natively without JavaScript or network access.
+	MathMathML = "mathml"
+)
+
+// Document is a rendered markdown document and the data passed to the page
+// template.
+type Document struct {
+	// Title comes from the frontmatter "title" field, or "Document".
+	Title string
+	// Content is the rendered body HTML.
+	Content template.HTML
+	// Styles is the theme CSS followed by any highlighting and custom CSS.
+	Styles template.CSS
+	// Scripts holds the <script> tags the content needs to display
+	// correctly, such as the Mermaid and KaTeX loaders.
+	Scripts template.HTML
+	// RequiredScripts names the client-side libraries included in Scripts
+	// ("mermaid", "katex").
+	RequiredScripts []string
+	// Toc is the table of contents as nested <ul> lists, for the page
+	// template to place. It is set when the table of contents is enabled
+	// and the document has no [TOC] or <!-- toc --> marker to put it at.
+	Toc template.HTML
+	// Metadata is the parsed YAML frontmatter.
+	Metadata map[string]interface{}
+	// SourcePath is the markdown file the document was converted from, as
+	// given to the converter, or "" for standard input and byte slices.
+	SourcePath string
+	// BuildDate is when the document was converted.
+	BuildDate time.Time
+	// Warnings describes problems that did not stop the conversion, such as
+	// math the MathML converter could not handle.
+	Warnings []string
+}
+
+// ConverterOptions configures a Converter. The zero value renders with the
+// dark theme and no optional features.
+type ConverterOptions struct {
+	// Theme selects the built-in stylesheet: "dark" (default), "light" or
+	// ThemeAuto. With ThemeCSSPath it only says which of these the custom
+	// stylesheet looks like, for the Mermaid diagram colours and the toggle.
+	Theme string
+
+	// ThemeCSSPath replaces the built-in theme stylesheet with a
+	// user-supplied one, read on every conversion.
+	ThemeCSSPath string
+
+	// EnableMermaid loads Mermaid to render ```mermaid code blocks.
+	EnableMermaid bool
+
+	// EnableMath renders $...$, $$...$$, \(...\) and \[...\] math.
+	EnableMath bool
+
+	// CopyButtons adds a script giving each highlighted code block a copy
+	// to clipboard button and a badge naming its language. Prompts such as
+	// "$ " are left out when copying shell sessions.
+	CopyButtons bool
+
+	// MathOutput selects how math is rendered: MathKaTeX (default) or
+	// MathMathML.
+	MathOutput string
+
+	// HighlightStyle names the Chroma style whose CSS is generated to
+	// colour code blocks. By default the theme picks one:
+	// DefaultDarkHighlightStyle for dark, DefaultLightHighlightStyle for
+	// light, and both for ThemeAuto.
+	HighlightStyle string
+
+	// CSSPath is a stylesheet appended after the theme CSS. It is read on
+	// every conversion so edits are picked up in watch and serve mode.
+	CSSPath string
+
+	// TemplatePath is a user-supplied html/template file, also read on
+	// every conversion. A template with content replaces
+	// templates/default.html; one holding only {{ define }} actions
+	// redefines the default template's "header", "nav" and "footer" blocks.
+	// Each *.html file in a partials directory next to it defines the
+	// template named after the file, so partials/footer.html replaces the
+	// footer block.
+	TemplatePath string
+
+	// Includes splices the markdown files named by !include and
+	// {{< include >}} directives into documents, and fills fenced code
+	// blocks with a file= attribute from the file named. It is off by
+	// default, as it lets a document read other files; they must be inside
+	// IncludeRoot and are named by relative paths.
+	Includes bool
+
+	// IncludeRoot is the directory files included into a document must be
+	// inside. By default it is the directory of the document. Input that
+	// did not come from a file can only include files when it is set, and
+	// they are then resolved against it.
+	IncludeRoot string
+
+	// WikiLinks turns [[Page]], [[Page#Heading]] and [[Page|label]] into
+	// links to the pages generated from the markdown files they name.
+	WikiLinks bool
+
+	// WikiLinkRoot is the directory searched, with its subdirectories, for
+	// the pages wikilinks name. By default it is the directory of the
+	// document. A filesystem root is never searched recursively, and input
+	// that did not come from a file only finds pages in the working
+	// directory itself.
+	WikiLinkRoot string
+
+	// RewriteLinks points links to local markdown files, such as
+	// [setup](setup.md#install), at the HTML pages generated from them.
+	// URLs, site-absolute paths and fragments are left alone. New enables
+	// it unless WithRewriteLinks(false) is given.
+	RewriteLinks bool
+
+	// OutputPath, if set, returns the HTML file generated from the markdown
+	// file at path, or "" when it is not known, in which case the page is
+	// assumed to be next to the markdown file. Links to markdown files and
+	// wikilinks are made relative to the page being written, so they stay
+	// correct when pages are written to another directory.
+	OutputPath func(path string) string
+
+	// Offline inlines the Mermaid and KaTeX runtimes bundled into the binary,
+	// and the KaTeX fonts as data URIs, instead of loading them from a CDN,
+	// so pages display without network access. Only the runtimes a document
+	// uses are included.
+	Offline bool
+
+	// InlineImages embeds local images referenced with ![]() as data URIs,
+	// so the page does not depend on files next to it. Paths are resolved
+	// relative to the input file, or the working directory for input that
+	// did not come from a file.
+	InlineImages bool
+
+	// InlineCSS replaces <link rel="stylesheet"> elements pointing at local
+	// files, in the document or the template, with the stylesheet contents.
+	InlineCSS bool
+
+	// MaxInlineSize is the largest file, in bytes, that InlineImages and
+	// InlineCSS embed; larger files stay linked and produce a warning. Zero
+	// means DefaultMaxInlineSize.
+	MaxInlineSize int64
+
+	// TOC adds a table of contents to every page, which the default
+	// template shows as a sidebar. Documents can override this with a
+	// "toc: true" or "toc: false" frontmatter field. A [TOC] or
+	// <!-- toc --> marker always places the table at the marker instead.
+	TOC bool
+
+	// TOCMinDepth and TOCMaxDepth are the heading levels included in the
+	// table of contents, DefaultTOCMinDepth and DefaultTOCMaxDepth when
+	// zero. Documents can set toc_min_depth and toc_max_depth in their
+	// frontmatter.
+	TOCMinDepth int
+	TOCMaxDepth int
+
+	// WarningHandler, if set, is called with every warning produced while
+	// converting a whole page. path is the input file, or "" for input that
+	// did not come from a file. It may be called from several goroutines at
+	// once when the Converter is shared.
+	WarningHandler func(path, message string)
+}
+
+// NewConverter returns a Converter using theme and no optional features.
+func NewConverter(theme string) *Converter {
+	return NewConverterWithOptions(ConverterOptions{
+		Theme:         theme,
+		EnableMermaid: false,
+		EnableMath:    false,
+	})
+}
+
+// NewConverterWithOptions returns a Converter configured by opts.
+func NewConverterWithOptions(opts ConverterOptions) *Converter {
+	highlightStyle := DefaultDarkHighlightStyle
+	if opts.HighlightStyle != "" {
+		highlightStyle = opts.HighlightStyle
+	}
+
+	extensions := []goldmark.Extender{
+		extension.GFM, // GitHub Flavored Markdown (includes tables, strikethrough, task lists)
+		extension.Footnote,
+		extension.DefinitionList,
+		extension.Typographer,
+		extension.Linkify,
+		highlighting.NewHighlighting(
+			highlighting.WithStyle(highlightStyle),
+			highlighting.WithFormatOptions(
+				html.WithClasses(true),
+				html.WithLineNumbers(false),
+			),
+			highlighting.WithWrapperRenderer(codeBlockWrapper(opts.CopyButtons)),
+		),
+		&tocExtension{},
+		&admonitionExtension{},
+	}
+	if opts.WikiLinks {
+		extensions = append(extensions, &wikiLinkExtension{})
+	}
+	if opts.EnableMath {
+		extensions = append(extensions, &mathExtension{})
+	}
+
+	md := goldmark.New(
+		goldmark.WithExtensions(extensions...),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+		),
+		goldmark.WithRendererOptions(
+			goldmarkhtml.WithXHTML(),
+			goldmarkhtml.WithUnsafe(), // Allow raw HTML in documents
+		),
+	)
+
+	tmpl := template.Must(template.New("default").Parse(defaultTemplate))
+	themeStyles, stylesErr := themeStylesheet(opts)
+
+	return &Converter{
+		markdown:      md,
+		template:      tmpl,
+		theme:         opts.Theme,
+		themeCSSPath:  opts.ThemeCSSPath,
+		enableMermaid: opts.EnableMermaid,
+		enableMath:    opts.EnableMath,
+		copyButtons:   opts.CopyButtons,
+		mathOutput:    opts.MathOutput,
+		themeStyles:   themeStyles,
+		stylesErr:     stylesErr,
+		cssPath:       opts.CSSPath,
+		templatePath:  opts.TemplatePath,
+		includes:      opts.Includes,
+		includeRoot:   opts.IncludeRoot,
+		wikiLinkRoot:  opts.WikiLinkRoot,
+		wikiPages:     &wikiPageCache{},
+		rewriteLinks:  opts.RewriteLinks,
+		outputPath:    opts.OutputPath,
+		offline:       opts.Offline,
+		inlineImages:  opts.InlineImages,
+		inlineCSS:     opts.InlineCSS,
+		maxInlineSize: opts.MaxInlineSize,
+		toc:           opts.TOC,
+		tocMinDepth:   opts.TOCMinDepth,
+		tocMaxDepth:   opts.TOCMaxDepth,
+		warn:          opts.WarningHandler,
+	}
+}
+
+// Convert renders the markdown file at inputPath and writes the HTML page to
+// outputPath, creating its directory if needed.
+func (c *Converter) Convert(inputPath, outputPath string) error {
+	output, err := c.renderFile(inputPath, outputPath)
+	if err != nil {
+		return err
+	}
+
+	// Create output directory if it doesn't exist
+	outputDir := filepath.Dir(outputPath)
+	if outputDir != "" && outputDir != "." {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Write output file
+	return os.WriteFile(outputPath, output, 0644)
+}
+
+// RenderFile converts the markdown file at inputPath into a complete HTML
+// page and returns it without writing anything to disk.
+func (c *Converter) RenderFile(inputPath string) ([]byte, error) {
+	return c.renderFile(inputPath, "")
+}
+
+// renderFile implements RenderFile for a page that will be written to
+// outputPath, or "" if not known.
+func (c *Converter) renderFile(inputPath, outputPath string) ([]byte, error) {
+	// Read input file
+	source, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	if err := c.render(context.Background(), inputPath, outputPath, source, &output); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+// ConvertReader reads markdown from r and writes the complete HTML page to
+// w. It is the streaming counterpart of Convert, used for stdin/stdout
+// pipelines. If ctx is cancelled, conversion stops with ctx.Err() at the
+// next read from r or between parsing and rendering, and nothing is written
+// to w.
+func (c *Converter) ConvertReader(ctx context.Context, r io.Reader, w io.Writer) error {
+	source, err := io.ReadAll(contextReader{ctx, r})
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	if err := c.render(ctx, "", "", source, &output); err != nil {
+		return err
+	}
+
+	_, err = output.WriteTo(w)
+	return err
+}
+
+// contextReader reads from r until ctx is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// ConvertBytes converts markdown source into a complete HTML page.
+func (c *Converter) ConvertBytes(src []byte) ([]byte, error) {
+	var output bytes.Buffer
+	if err := c.render(context.Background(), "", "", src, &output); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+// RenderDocument converts markdown source into a Document without applying
+// the page template, for callers that embed the body HTML in their own
+// pages. Styles holds the CSS the page template would have used.
+func (c *Converter) RenderDocument(ctx context.Context, src []byte) (*Document, error) {
+	return c.renderDocument(ctx, "", "", src)
+}
+
+// renderDocument implements RenderDocument. path is the input file, which
+// local images are resolved against, or "" for the working directory.
+// output is the file the page is written to, which links to other pages
+// are relative to, or "" if not known.
+func (c *Converter) renderDocument(ctx context.Context, path, output string, src []byte) (*Document, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if c.stylesErr != nil {
+		return nil, c.stylesErr
+	}
+
+	// Parse frontmatter
+	doc, markdownContent := c.parseFrontmatter(src)
+	doc.SourcePath = path
+	doc.BuildDate = time.Now()
+
+	// Splice in included files, then convert markdown to HTML
+	root, markdownContent, sources, err := c.parse(path, src, markdownContent)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	doc.Toc = template.HTML(buildTOC(root, markdownContent, c.documentTOCSettings(doc.Metadata)))
+
+	if c.includes {
+		includeRoot, dir := c.includeDirs(path)
+		markdownContent, err = includeSnippets(root, markdownContent, includeRoot, dir, sources.location)
+		if err != nil {
+			return nil, err
+		}
+	}
+	doc.Warnings = append(doc.Warnings, prepareCodeBlocks(root, markdownContent, sources.where)...)
+
+	linker, err := c.newPageLinker(path, output)
+	if err != nil {
+		return nil, err
+	}
+	warnings, err := c.resolveWikiLinks(root, path, linker, sources.where)
+	if err != nil {
+		return nil, err
+	}
+	doc.Warnings = append(doc.Warnings, warnings...)
+	if c.rewriteLinks {
+		if err := rewriteMarkdownLinks(root, path, linker); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.inlineImages {
+		doc.Warnings = append(doc.Warnings, inlineImages(root, baseDir(path), c.inlineLimit())...)
+	}
+
+	if c.enableMath && c.mathOutput == MathMathML {
+		// Math nodes carry their MathML into the renderer.
+		if unsupported := convertMathML(root, markdownContent); len(unsupported) > 0 {
+			doc.Warnings = append(doc.Warnings, unsupportedMathWarning(sources, unsupported))
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := c.markdown.Renderer().Render(&buf, markdownContent, root); err != nil {
+		return nil, err
+	}
+
+	doc.Content = template.HTML(buf.String())
+
+	// Inject scripts if needed
+	if err := c.injectScripts(doc, root, markdownContent); err != nil {
+		return nil, err
+	}
+
+	// Replace the built-in theme with a custom stylesheet
+	if c.themeCSSPath != "" {
+		themeCSS, err := os.ReadFile(c.themeCSSPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read theme CSS: %w", err)
+		}
+		doc.Styles = template.CSS(themeCSS) + doc.Styles
+	}
+
+	// Add custom styles after the theme
+	extraCSS, err := c.extraStyles()
+	if err != nil {
+		return nil, err
+	}
+	doc.Styles += template.CSS(extraCSS)
+
+	return doc, ctx.Err()
+}
+
+// parse splices the files included by markdownContent, the markdown of the
+// document at path after the frontmatter of src, into it and parses the
+// result. It returns the syntax tree, the expanded markdown and where each
+// part of it came from.
+func (c *Converter) parse(path string, src, markdownContent []byte) (ast.Node, []byte, *sourceMap, error) {
+	firstLine := bytes.Count(src[:len(src)-len(markdownContent)], []byte("\n")) + 1
+	markdownContent, sources, err := c.expandIncludes(path, markdownContent, firstLine)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return c.markdown.Parser().Parse(text.NewReader(markdownContent)), markdownContent, sources, nil
+}
+
+// render converts markdown source into a complete HTML page written to w.
+// path names the input file for warnings; outputPath is the file w writes
+// to, or "" if not known.
+func (c *Converter) render(ctx context.Context, path, outputPath string, source []byte, w io.Writer) error {
+	doc, err := c.renderDocument(ctx, path, outputPath, source)
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := c.loadTemplate()
+	if err != nil {
+		return err
+	}
+
+	// Render template
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, doc); err != nil {
+		return err
+	}
+
+	output := page.Bytes()
+	if c.inlineCSS {
+		var warnings []string
+		output, warnings = inlineStylesheets(output, baseDir(path), c.inlineLimit())
+		doc.Warnings = append(doc.Warnings, warnings...)
+	}
+
+	if c.warn != nil {
+		for _, warning := range doc.Warnings {
+			c.warn(path, warning)
+		}
+	}
+
+	_, err = w.Write(output)
+	return err
+}
+
+// inlineLimit returns the size limit for inlined files.
+func (c *Converter) inlineLimit() int64 {
+	if c.maxInlineSize > 0 {
+		return c.maxInlineSize
+	}
+	return DefaultMaxInlineSize
+}
+
+// baseDir returns the directory relative paths in the input at path are
+// resolved against.
+func baseDir(path string) string {
+	if path == "" {
+		return "."
+	}
+	return filepath.Dir(path)
+}
+
+// extraStyles returns the CSS added after the theme and its highlighting:
+// the custom CSS file.
+func (c *Converter) extraStyles() (string, error) {
+	var css strings.Builder
+
+	if c.cssPath != "" {
+		custom, err := os.ReadFile(c.cssPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read custom CSS: %w", err)
+		}
+		css.WriteString("\n")
+		css.Write(custom)
+	}
+
+	return css.String(), nil
+}
+
+// loadTemplate returns the page template, parsing the custom template file
+// and its partials over the default template when one is configured.
+func (c *Converter) loadTemplate() (*template.Template, error) {
+	if c.templatePath == "" {
+		return c.template, nil
+	}
+
+	source, err := os.ReadFile(c.templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	// The custom template replaces the default one only if it has content
+	// outside {{ define }} actions, so it may redefine just the blocks.
+	tmpl := template.Must(template.New(filepath.Base(c.templatePath)).Parse(defaultTemplate))
+	if _, err := tmpl.Parse(string(source)); err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	partials, err := c.templatePartials()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range partials {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template partial: %w", err)
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if _, err := tmpl.New(name).Parse(string(source)); err != nil {
+			return nil, fmt.Errorf("failed to parse template partial %s: %w", path, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// templatePartials returns the *.html files in the partials directory next
+// to the custom template.
+func (c *Converter) templatePartials() ([]string, error) {
+	if c.templatePath == "" {
+		return nil, nil
+	}
+	return filepath.Glob(filepath.Join(filepath.Dir(c.templatePath), "partials", "*.html"))
+}
+
+// sourceLocation formats a line of the input at path as path:line for
+// errors, or "line N" for input that did not come from a file.
+func sourceLocation(path string, line int) string {
+	if path == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return fmt.Sprintf("%s:%d", path, line)
+}
+
+// unsupportedMathWarning lists the LaTeX commands the MathML converter could
+// not handle, with the line each one is on.
+func unsupportedMathWarning(sources *sourceMap, unsupported []unsupportedMath) string {
+	var list []string
+	seen := make(map[string]bool)
+	for _, u := range unsupported {
+		item := fmt.Sprintf("%s (%s)", u.command, sources.where(u.offset))
+		if !seen[item] {
+			seen[item] = true
+			list = append(list, item)
+		}
+	}
+	return "unsupported LaTeX in math, shown as errors: " + strings.Join(list, ", ")
+}
+
+func (c *Converter) parseFrontmatter(source []byte) (*Document, []byte) {
+	doc := &Document{
+		Title:    "Document",
+		Styles:   template.CSS(c.themeStyles),
+		Metadata: make(map[string]interface{}),
+	}
+
+	content := source
+	str := string(source)
+
+	// Check for YAML frontmatter (--- at start)
+	if strings.HasPrefix(str, "---\n") || strings.HasPrefix(str, "---\r\n") {
+		parts := strings.SplitN(str, "\n", 2)
+		if len(parts) == 2 {
+			rest := parts[1]
+			endIdx := strings.Index(rest, "\n---\n")
+			if endIdx == -1 {
+				endIdx = strings.Index(rest, "\n---\r\n")
+			}
+			if endIdx == -1 {
+				endIdx = strings.Index(rest, "\r\n---\r\n")
+			}
+
+			if endIdx != -1 {
+				frontmatter := rest[:endIdx]
+				content = []byte(rest[endIdx+5:]) // Skip past "---\n"
+
+				// Parse YAML
+				var metadata map[string]interface{}
+				if err := yaml.Unmarshal([]byte(frontmatter), &metadata); err == nil {
+					doc.Metadata = metadata
+					if title, ok := metadata["title"].(string); ok {
+						doc.Title = title
+					}
+				}
+			}
+		}
+	}
+
+	return doc, content
+}
+
+func (c *Converter) injectScripts(doc *Document, root ast.Node, source []byte) error {
+	var scripts []string
+	hasMermaid, hasMath, hasTOC, hasCode := false, false, doc.Toc != "", false
+
+	// The auto theme's toggle goes first so it applies before the page shows
+	if c.theme == ThemeAuto {
+		scripts = append(scripts, themeScript)
+	}
+
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.FencedCodeBlock:
+			isMermaid := string(node.Language(source)) == "mermaid"
+			hasMermaid = hasMermaid || isMermaid
+			hasCode = hasCode || !isMermaid
+		case *MathBlock, *MathInline:
+			hasMath = true
+		case *TOC:
+			hasTOC = hasTOC || node.html != ""
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Check for Mermaid diagrams
+	if c.enableMermaid && hasMermaid {
+		mermaidTheme := "dark"
+		switch c.theme {
+		case "light":
+			mermaidTheme = "default"
+		case ThemeAuto:
+			mermaidTheme = ThemeAuto // resolved in the browser
+		}
+		script := strings.Replace(GetMermaidScript(), "{{THEME}}", mermaidTheme, 1)
+		if c.offline {
+			var err error
+			if script, err = offlineMermaidScript(script); err != nil {
+				return err
+			}
+		}
+		scripts = append(scripts, script)
+		doc.RequiredScripts = append(doc.RequiredScripts, "mermaid")
+	}
+
+	// Check for Math expressions; MathML needs no script
+	if c.enableMath && hasMath && c.mathOutput != MathMathML {
+		script := GetKatexScript()
+		if c.offline {
+			var err error
+			if script, err = offlineKatexScript(); err != nil {
+				return err
+			}
+		}
+		scripts = append(scripts, script)
+		doc.RequiredScripts = append(doc.RequiredScripts, "katex")
+	}
+
+	// Copy buttons need no external script, so they work offline too
+	if c.copyButtons && hasCode {
+		scripts = append(scripts, copyScript)
+	}
+
+	// Highlight the current section in the table of contents
+	if hasTOC {
+		scripts = append(scripts, tocScript)
+	}
+
+	if len(scripts) > 0 {
+		doc.Scripts = template.HTML(strings.Join(scripts, "\n"))
+	}
+	return nil
+}
+
+// Dependencies returns the local files the HTML generated from inputPath
+// depends on: the markdown file itself, the theme, custom CSS, template and
+// partial files, the markdown files it includes, any images it references by
+// relative path and the files its code blocks include. Watch mode uses it to
+// know which files to monitor.
+func (c *Converter) Dependencies(inputPath string) ([]string, error) {
+	source, err := os.ReadFile(inputPath)
+	if err != nil {
+		return nil, err
+	}
+
+	_, markdownContent := c.parseFrontmatter(source)
+	// A broken include is reported by the conversion; the files included
+	// before it are still worth watching.
+	markdownContent, sources, _ := c.expandIncludes(inputPath, markdownContent, 1)
+	root := c.markdown.Parser().Parse(text.NewReader(markdownContent))
+
+	deps := []string{inputPath}
+	seen := map[string]bool{inputPath: true}
+	partials, err := c.templatePartials()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range append(append([]string{c.themeCSSPath, c.cssPath, c.templatePath}, partials...), sources.files()...) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			deps = append(deps, path)
+		}
+	}
+	dir := filepath.Dir(inputPath)
+
+	err = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var path string
+		switch node := n.(type) {
+		case *ast.Image:
+			path, _ = localPath(dir, string(node.Destination))
+		case *ast.FencedCodeBlock:
+			if s, ok := codeSnippet(node, markdownContent); ok && s.file != "" && c.includes {
+				includeRoot, _ := c.includeDirs(inputPath)
+				path, _ = confinedPath(includeRoot, dir, s.file)
+			}
+		}
+		if path != "" && !seen[path] {
+			seen[path] = true
+			deps = append(deps, path)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	return deps, err
+}
+
+// localPath resolves a link destination relative to baseDir. It reports false
+// for URLs with a scheme, site-absolute paths and fragment-only links.
+func localPath(baseDir, dest string) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return "", false
+	}
+	return filepath.Join(baseDir, filepath.FromSlash(u.Path)), true
+}
@@ -1,11 +1,15 @@
-package internal
+package mkdown
 
 import (
+	"context"
+	"fmt"
 	"html/template"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewConverter(t *testing.T) {
@@ -417,3 +421,265 @@ func TestTableExtension(t *testing.T) {
 	}
 }
 
+func TestDependencies(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "test.md")
+
+	content := `# Images
+
+![local](images/diagram.png)
+![again](images/diagram.png)
+![remote](https://example.com/logo.png)
+![absolute](/static/logo.png)
+[a link](other.md)`
+
+	if err := os.WriteFile(inputPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewConverter("dark")
+	deps, err := c.Dependencies(inputPath)
+	if err != nil {
+		t.Fatalf("Dependencies failed: %v", err)
+	}
+
+	want := []string{inputPath, filepath.Join(tmpDir, "images", "diagram.png")}
+	if strings.Join(deps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected dependencies %v, got %v", want, deps)
+	}
+}
+
+func TestCustomStylesAndTemplate(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "test.md")
+	cssPath := filepath.Join(tmpDir, "custom.css")
+	templatePath := filepath.Join(tmpDir, "page.html")
+
+	files := map[string]string{
+		inputPath:    "---\ntitle: Custom\n---\n# Hello",
+		cssPath:      ".custom-rule { color: red; }",
+		templatePath: `<html><head><style>{{ .Styles }}</style></head><body class="custom">{{ .Title }} {{ .Content }}</body></html>`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewConverterWithOptions(ConverterOptions{
+		Theme:          "dark",
+		HighlightStyle: "github",
+		CSSPath:        cssPath,
+		TemplatePath:   templatePath,
+	})
+
+	output, err := c.RenderFile(inputPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+
+	outputStr := string(output)
+	checks := []string{
+		`<body class="custom">Custom`,
+		".custom-rule { color: red; }",
+		"/* Background */ .bg",
+		"#0d1117", // theme CSS is still included
+	}
+
+	for _, check := range checks {
+		if !strings.Contains(outputStr, check) {
+			t.Errorf("output missing: %s", check)
+		}
+	}
+
+	if strings.Index(outputStr, "#0d1117") > strings.Index(outputStr, ".custom-rule") {
+		t.Error("custom CSS should come after the theme")
+	}
+
+	bad := NewConverterWithOptions(ConverterOptions{Theme: "dark", HighlightStyle: "no-such-style"})
+	if _, err := bad.RenderFile(inputPath); err == nil {
+		t.Error("expected an error for an unknown highlight style")
+	}
+}
+
+func TestTemplateData(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "guide.md")
+	templatePath := filepath.Join(tmpDir, "page.html")
+
+	files := map[string]string{
+		inputPath:    "---\ntitle: Guide\nauthor: Ada\ntags: [go, docs]\n---\n# Hello",
+		templatePath: `<title>{{ .Title }}</title><p>by {{ .Metadata.author }}{{ range .Metadata.tags }} #{{ . }}{{ end }}</p><p>{{ .SourcePath }}</p><p>built {{ .BuildDate.Format "2006" }}</p>{{ .Content }}`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, err := New(WithTemplate(templatePath)).RenderFile(inputPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"<title>Guide</title>",
+		"<p>by Ada #go #docs</p>",
+		"<p>" + inputPath + "</p>",
+		fmt.Sprintf("<p>built %d</p>", time.Now().Year()),
+		`<h1 id="hello">Hello</h1>`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("expected %q in output, got: %s", want, output)
+		}
+	}
+}
+
+func TestTemplateBlocksAndPartials(t *testing.T) {
+	tmpDir := t.TempDir()
+	inputPath := filepath.Join(tmpDir, "doc.md")
+	templatePath := filepath.Join(tmpDir, "brand.html")
+	partialsDir := filepath.Join(tmpDir, "partials")
+	footerPath := filepath.Join(partialsDir, "footer.html")
+	if err := os.Mkdir(partialsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		inputPath:    "---\ntitle: Branded\n---\n## Section",
+		templatePath: `{{ define "header" }}<header class="brand">{{ .Title }}</header>{{ end }}`,
+		footerPath:   `<footer>Generated from {{ .SourcePath }}</footer>`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := New(WithTemplate(templatePath), WithTOC(true))
+	output, err := c.RenderFile(inputPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+
+	outputStr := string(output)
+	for _, want := range []string{
+		"<!DOCTYPE html>", // default layout is kept
+		`<body class="has-toc"><header class="brand">Branded</header>`,
+		`<nav class="toc toc-sidebar"`,
+		"<footer>Generated from " + inputPath + "</footer>",
+	} {
+		if !strings.Contains(outputStr, want) {
+			t.Errorf("expected %q in output, got: %s", want, outputStr)
+		}
+	}
+
+	deps, err := c.Dependencies(inputPath)
+	if err != nil {
+		t.Fatalf("Dependencies failed: %v", err)
+	}
+	if want := []string{inputPath, templatePath, footerPath}; strings.Join(deps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected dependencies %v, got %v", want, deps)
+	}
+
+	if err := os.WriteFile(footerPath, []byte(`<footer>{{ .Missing`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenderFile(inputPath); err == nil || !strings.Contains(err.Error(), footerPath) {
+		t.Errorf("expected a parse error naming the partial, got %v", err)
+	}
+}
+
+func TestConvertReader(t *testing.T) {
+	c := NewConverter("dark")
+
+	var out strings.Builder
+	if err := c.ConvertReader(context.Background(), strings.NewReader("# Streamed"), &out); err != nil {
+		t.Fatalf("ConvertReader failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Streamed</h1>") {
+		t.Error("rendered heading not found in output")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	out.Reset()
+	if err := c.ConvertReader(ctx, strings.NewReader("# Cancelled"), &out); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Error("nothing should be written when the context is cancelled")
+	}
+
+	// Cancelling while the input is read stops reading it.
+	ctx, cancel = context.WithCancel(context.Background())
+	r := &cancellingReader{remaining: 1000, cancel: cancel}
+	out.Reset()
+	if err := c.ConvertReader(ctx, r, &out); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if r.remaining != 999 || out.Len() != 0 {
+		t.Errorf("expected reading to stop after the first chunk, %d left, %d bytes written", r.remaining, out.Len())
+	}
+}
+
+// cancellingReader returns a line of markdown for each read, cancelling
+// its context after the first.
+type cancellingReader struct {
+	remaining int
+	cancel    context.CancelFunc
+}
+
+func (r *cancellingReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	r.remaining--
+	r.cancel()
+	return copy(p, "Some text.\n"), nil
+}
+
+func TestConvertBytes(t *testing.T) {
+	c := NewConverter("dark")
+
+	output, err := c.ConvertBytes([]byte("---\ntitle: In Memory\n---\n# Bytes"))
+	if err != nil {
+		t.Fatalf("ConvertBytes failed: %v", err)
+	}
+
+	outputStr := string(output)
+	for _, check := range []string{"<!DOCTYPE html>", "<title>In Memory</title>", "Bytes</h1>"} {
+		if !strings.Contains(outputStr, check) {
+			t.Errorf("output missing: %s", check)
+		}
+	}
+}
+
+func TestRenderDocument(t *testing.T) {
+	c := NewConverterWithOptions(ConverterOptions{Theme: "light", EnableMermaid: true})
+
+	source := "---\ntitle: Structured\nauthor: Jane\n---\n# Heading\n\n```mermaid\ngraph TD; A-->B\n```\n"
+	doc, err := c.RenderDocument(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+
+	if doc.Title != "Structured" {
+		t.Errorf("expected title %q, got %q", "Structured", doc.Title)
+	}
+	if doc.Metadata["author"] != "Jane" {
+		t.Errorf("expected author metadata, got %v", doc.Metadata)
+	}
+	if !strings.Contains(string(doc.Content), "Heading</h1>") {
+		t.Error("body HTML missing heading")
+	}
+	if strings.Contains(string(doc.Content), "<html") {
+		t.Error("body HTML should not include the page template")
+	}
+	if len(doc.RequiredScripts) != 1 || doc.RequiredScripts[0] != "mermaid" {
+		t.Errorf("expected mermaid to be required, got %v", doc.RequiredScripts)
+	}
+	if !strings.Contains(string(doc.Styles), "#ffffff") {
+		t.Error("light theme styles missing")
+	}
+}
@@ -0,0 +1,24 @@
+// Package mkdown converts markdown documents into standalone, styled HTML
+// pages. It is the library behind the mkdown command-line tool, so documents
+// rendered through it look exactly like the ones the CLI produces.
+//
+// A Converter is created once and reused for any number of documents:
+//
+//	conv := mkdown.New(
+//		mkdown.WithTheme("light"),
+//		mkdown.WithMermaid(true),
+//	)
+//
+//	page, err := conv.ConvertBytes([]byte("# Hello"))
+//
+// Besides whole pages, a Converter can stream (ConvertReader), convert files
+// on disk (Convert, RenderFile) or return the rendered body and metadata as a
+// Document (RenderDocument) for embedding in other pages.
+//
+// # Compatibility
+//
+// This package follows semantic versioning. Within a major version, exported
+// identifiers are only ever added: existing functions, methods, options and
+// Document fields keep their names, signatures and meaning. The exact HTML
+// and CSS produced may change between minor versions as rendering improves.
+package mkdown
@@ -0,0 +1,23 @@
+package mkdown_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ekinertac/mkdown/mkdown"
+)
+
+func ExampleNew() {
+	conv := mkdown.New(mkdown.WithTheme("light"))
+
+	doc, err := conv.RenderDocument(context.Background(), []byte("---\ntitle: Greeting\n---\n# Hello"))
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(doc.Title)
+	fmt.Println(doc.Content)
+	// Output:
+	// Greeting
+	// <h1 id="hello">Hello</h1>
+}
@@ -0,0 +1,65 @@
+package mkdown
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Chroma styles paired with the built-in themes when no highlight style is
+// configured. The auto theme uses both.
+const (
+	DefaultDarkHighlightStyle  = "monokai"
+	DefaultLightHighlightStyle = "github"
+)
+
+// themeStylesheet returns the CSS of the built-in theme followed by the
+// syntax highlighting CSS of its Chroma style. With a custom theme
+// stylesheet it returns only the highlighting, for the theme's appearance.
+func themeStylesheet(opts ConverterOptions) (string, error) {
+	lightStyle, darkStyle := DefaultLightHighlightStyle, DefaultDarkHighlightStyle
+	if opts.HighlightStyle != "" {
+		lightStyle, darkStyle = opts.HighlightStyle, opts.HighlightStyle
+	}
+	light, dark := lightThemeCSS, darkThemeCSS
+	if opts.ThemeCSSPath != "" {
+		light, dark = "", ""
+	}
+
+	switch opts.Theme {
+	case "light":
+		css, err := HighlightCSS(lightStyle)
+		return light + css, err
+	case ThemeAuto:
+		lightCSS, err := HighlightCSS(lightStyle)
+		if err != nil {
+			return "", err
+		}
+		darkCSS, err := HighlightCSS(darkStyle)
+		if err != nil {
+			return "", err
+		}
+		return combineThemes(light+lightCSS, dark+darkCSS), nil
+	default:
+		css, err := HighlightCSS(darkStyle)
+		return dark + css, err
+	}
+}
+
+// HighlightCSS returns the stylesheet colouring code blocks with the named
+// Chroma style.
+func HighlightCSS(name string) (string, error) {
+	style, ok := styles.Registry[name]
+	if !ok {
+		return "", fmt.Errorf("unknown highlight style '%s'", name)
+	}
+
+	var css strings.Builder
+	fmt.Fprintf(&css, "\n/* Syntax highlighting: %s */\n", name)
+	if err := html.New(html.WithClasses(true)).WriteCSS(&css, style); err != nil {
+		return "", err
+	}
+	return css.String(), nil
+}
@@ -0,0 +1,70 @@
+package mkdown
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestThemeCSS(t *testing.T) {
+	tmpDir := t.TempDir()
+	themePath := filepath.Join(tmpDir, "theme.css")
+	if err := os.WriteFile(themePath, []byte("body { background: #f4ecd8; }"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(WithTheme("light"), WithThemeCSS(themePath), WithMermaid(true))
+	output, err := c.ConvertBytes([]byte("```mermaid\ngraph TD\n```\n"))
+	if err != nil {
+		t.Fatalf("ConvertBytes failed: %v", err)
+	}
+
+	outputStr := string(output)
+	if !strings.Contains(outputStr, "#f4ecd8") {
+		t.Error("theme stylesheet not included")
+	}
+	if strings.Contains(outputStr, "nav.toc") {
+		t.Error("built-in theme CSS should be replaced")
+	}
+	if !strings.Contains(outputStr, "/* Syntax highlighting: "+DefaultLightHighlightStyle+" */") {
+		t.Error("highlighting should still match the theme's light appearance")
+	}
+	if !strings.Contains(outputStr, "theme: 'default'") {
+		t.Error("Mermaid should follow the theme's light appearance")
+	}
+
+	missing := New(WithThemeCSS(filepath.Join(tmpDir, "missing.css")))
+	if _, err := missing.ConvertBytes([]byte("# Hi")); err == nil {
+		t.Error("expected an error for a missing theme stylesheet")
+	}
+}
+
+func TestHighlightStylePairing(t *testing.T) {
+	tests := []struct {
+		opts []Option
+		want []string
+	}{
+		{nil, []string{"/* Syntax highlighting: monokai */", "#272822"}},
+		{[]Option{WithTheme("light")}, []string{"/* Syntax highlighting: github */"}},
+		{[]Option{WithTheme(ThemeAuto)}, []string{"--mkdown-auto-", "#272822"}},
+		{[]Option{WithTheme("light"), WithHighlightStyle("dracula")}, []string{"/* Syntax highlighting: dracula */"}},
+	}
+
+	for _, tt := range tests {
+		doc, err := New(tt.opts...).RenderDocument(context.Background(), []byte("```go\nx := 1\n```"))
+		if err != nil {
+			t.Fatalf("RenderDocument failed: %v", err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(doc.Styles), want) {
+				t.Errorf("expected %q in styles", want)
+			}
+		}
+	}
+
+	if _, err := HighlightCSS("no-such-style"); err == nil {
+		t.Error("expected an error for an unknown style")
+	}
+}
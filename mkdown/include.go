@@ -0,0 +1,407 @@
+package mkdown
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxIncludeDepth bounds nested includes, in case paths that differ in
+// spelling hide a cycle.
+const maxIncludeDepth = 32
+
+// includeDirective matches a line including another markdown file, either
+// {{< include "sections/intro.md" >}} or !include sections/intro.md, each
+// optionally followed by options such as shift=1.
+var includeDirective = regexp.MustCompile(`^ {0,3}(?:\{\{<\s*include\s+"([^"]+)"((?:\s+[\w-]+=[^\s>]+)*)\s*>\}\}|!include\s+(\S+)((?:\s+[\w-]+=\S+)*))\s*$`)
+
+// atxHeading matches the hashes of an ATX heading.
+var atxHeading = regexp.MustCompile(`^( {0,3})(#{1,6})([ \t]|$)`)
+
+// codeFence matches the opening or closing fence of a fenced code block.
+var codeFence = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+
+// Link destinations rewritten in included files: inline links and images,
+// reference definitions and the src and href attributes of raw HTML.
+var (
+	inlineLinkDest = regexp.MustCompile(`(\]\(\s*)(<[^>\n]*>|[^\s)]+)`)
+	referenceDest  = regexp.MustCompile(`^( {0,3}\[[^\]]+\]:\s*)(<[^>\n]*>|\S+)`)
+	htmlLinkDest   = regexp.MustCompile(`(\s(?:src|href)=")([^"]*)(")`)
+	fenceFileDest  = regexp.MustCompile(`([\s{,]file=)("[^"]*"|[^\s,}]+)`)
+)
+
+// sourceChunk records that the expanded markdown from offset on comes from
+// the file at path, starting at line. path is "" for the document itself.
+type sourceChunk struct {
+	offset int
+	path   string
+	line   int
+}
+
+// sourceMap maps offsets in markdown expanded by expandIncludes back to the
+// file and line they came from.
+type sourceMap struct {
+	path    string // the document, or "" for input that did not come from a file
+	content []byte
+	chunks  []sourceChunk
+}
+
+// position returns the file, "" for the document itself, and line that
+// offset in the expanded markdown comes from.
+func (m *sourceMap) position(offset int) (string, int) {
+	i := sort.Search(len(m.chunks), func(i int) bool { return m.chunks[i].offset > offset }) - 1
+	if i < 0 || offset > len(m.content) {
+		return "", 0
+	}
+	chunk := m.chunks[i]
+	return chunk.path, chunk.line + bytes.Count(m.content[chunk.offset:offset], []byte("\n"))
+}
+
+// where describes the position of offset for warnings, which already name
+// the document: "line 12" in the document itself, or "sections/intro.md:4"
+// in an included file.
+func (m *sourceMap) where(offset int) string {
+	path, line := m.position(offset)
+	if path == "" {
+		return fmt.Sprintf("line %d", line)
+	}
+	return sourceLocation(path, line)
+}
+
+// location describes the position of offset for errors: path:line, in the
+// document or the included file it comes from.
+func (m *sourceMap) location(offset int) string {
+	path, line := m.position(offset)
+	if path == "" {
+		path = m.path
+	}
+	return sourceLocation(path, line)
+}
+
+// files returns the files included into the document.
+func (m *sourceMap) files() []string {
+	var files []string
+	seen := map[string]bool{"": true}
+	for _, chunk := range m.chunks {
+		if !seen[chunk.path] {
+			seen[chunk.path] = true
+			files = append(files, chunk.path)
+		}
+	}
+	return files
+}
+
+// includer splices included files into a document.
+type includer struct {
+	path    string // the document, or "" for input that did not come from a file
+	rootDir string // directory links in the output are relative to
+	allowed string // directory included files must be inside
+	out     bytes.Buffer
+	chunks  []sourceChunk
+}
+
+// includeDirs returns the directory the files included into the document
+// at path must be inside, and the directory the document's own includes
+// are resolved against. Both are the IncludeRoot option for input that did
+// not come from a file, and so "" when it is not set.
+func (c *Converter) includeDirs(path string) (root, dir string) {
+	if path == "" {
+		return c.includeRoot, c.includeRoot
+	}
+	root = c.includeRoot
+	if root == "" {
+		root = baseDir(path)
+	}
+	return root, baseDir(path)
+}
+
+// expandIncludes replaces include directives in content, the markdown of
+// the document at path after its frontmatter, with the files they name,
+// when includes are enabled. Included files are resolved relative to the
+// file including them and must be inside the directory includeDirs
+// returns; their frontmatter is dropped, their headings are shifted down
+// by the shift option, and their relative links, images and code block
+// file= paths are rewritten to stay correct from the document. firstLine
+// is the line of path content starts on. Errors give the file and line of
+// the directive.
+func (c *Converter) expandIncludes(path string, content []byte, firstLine int) ([]byte, *sourceMap, error) {
+	if !c.includes {
+		return content, &sourceMap{path: path, content: content, chunks: []sourceChunk{{line: firstLine}}}, nil
+	}
+	root, dir := c.includeDirs(path)
+	in := &includer{path: path, rootDir: dir, allowed: root}
+	var stack []string
+	if path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			stack = append(stack, abs)
+		}
+	}
+	err := in.expand(content, "", firstLine, stack, 0)
+	expanded := in.out.Bytes()
+	return expanded, &sourceMap{path: path, content: expanded, chunks: in.chunks}, err
+}
+
+// expand writes content, which comes from file ("" for the document)
+// starting at firstLine, with its include directives expanded. stack holds
+// the absolute paths of the files being expanded, outermost first.
+func (in *includer) expand(content []byte, file string, firstLine int, stack []string, shift int) error {
+	dir := in.rootDir
+	if file != "" {
+		dir = filepath.Dir(file)
+	}
+	in.mark(file, firstLine)
+
+	var fence string
+	lines := strings.SplitAfter(string(content), "\n")
+	for i, line := range lines {
+		number := firstLine + i
+		trimmed := strings.TrimRight(line, "\r\n")
+
+		if marker := codeFence.FindStringSubmatch(trimmed); marker != nil {
+			switch {
+			case fence == "":
+				fence = marker[1]
+				if file != "" {
+					line = fenceFileDest.ReplaceAllStringFunc(line, func(attr string) string {
+						return in.rewriteMatch(fenceFileDest, attr, dir)
+					})
+				}
+			case marker[1][0] == fence[0] && len(marker[1]) >= len(fence) && strings.TrimSpace(trimmed[len(marker[0]):]) == "":
+				fence = ""
+			}
+			in.out.WriteString(line)
+			continue
+		}
+		if fence != "" {
+			in.out.WriteString(line)
+			continue
+		}
+
+		if match := includeDirective.FindStringSubmatch(trimmed); match != nil {
+			target, options := match[1], match[2]
+			if target == "" {
+				target, options = match[3], match[4]
+			}
+			location := sourceLocation(in.displayPath(file), number)
+			if err := in.include(target, options, dir, stack, shift, location); err != nil {
+				return err
+			}
+			in.mark(file, number+1)
+			continue
+		}
+
+		if file != "" {
+			line = in.rewriteLinks(line, dir)
+		}
+		if shift > 0 {
+			line = shiftHeading(line, shift)
+		}
+		in.out.WriteString(line)
+	}
+	return nil
+}
+
+// include expands the file named by an include directive found at
+// location.
+func (in *includer) include(target, options, dir string, stack []string, shift int, location string) error {
+	for _, option := range strings.Fields(options) {
+		name, value, _ := strings.Cut(option, "=")
+		if name != "shift" {
+			return fmt.Errorf("%s: unknown include option '%s' (valid: shift)", location, name)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 5 {
+			return fmt.Errorf("%s: include shift must be a number from 0 to 5, got '%s'", location, value)
+		}
+		shift += n
+	}
+
+	path, err := confinedPath(in.allowed, dir, target)
+	if err != nil {
+		return fmt.Errorf("%s: cannot include %s: %w", location, target, err)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("%s: cannot include %s: %w", location, target, err)
+	}
+	for i, open := range stack {
+		if open == abs {
+			cycle := make([]string, 0, len(stack)-i+1)
+			for _, p := range stack[i:] {
+				cycle = append(cycle, in.relative(p))
+			}
+			return fmt.Errorf("%s: include cycle: %s -> %s", location, strings.Join(cycle, " -> "), in.relative(abs))
+		}
+	}
+	if len(stack) >= maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested more than %d deep", location, maxIncludeDepth)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%s: cannot include %s: %w", location, target, unwrapPathError(err))
+	}
+	body, firstLine := stripFrontmatter(data)
+	if len(body) > 0 && body[len(body)-1] != '\n' {
+		body = append(body, '\n')
+	}
+	return in.expand(body, path, firstLine, append(stack[:len(stack):len(stack)], abs), shift)
+}
+
+// confinedPath returns the file named by target, a relative path written
+// in a file in dir, checking that it is inside root even after following
+// symbolic links, so that a document cannot read arbitrary files.
+func confinedPath(root, dir, target string) (string, error) {
+	if root == "" {
+		return "", errors.New("input that did not come from a file can only include files when an include root is set")
+	}
+	if filepath.IsAbs(target) || strings.HasPrefix(target, "/") {
+		return "", errors.New("absolute paths are not allowed")
+	}
+	path := filepath.Join(dir, filepath.FromSlash(target))
+	inside, err := within(root, path)
+	if err == nil && inside {
+		// Check where a symbolic link leads; a missing file is reported
+		// when it is read.
+		if resolved, linkErr := filepath.EvalSymlinks(path); linkErr == nil {
+			realRoot, rootErr := filepath.EvalSymlinks(root)
+			if rootErr != nil {
+				realRoot = root
+			}
+			inside, err = within(realRoot, resolved)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !inside {
+		return "", fmt.Errorf("outside %s", root)
+	}
+	return path, nil
+}
+
+// within reports whether path is root or inside it.
+func within(root, path string) (bool, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absRoot, abs)
+	if err != nil {
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// mark records that the output from here on comes from file at line.
+func (in *includer) mark(file string, line int) {
+	chunk := sourceChunk{offset: in.out.Len(), path: file, line: line}
+	if n := len(in.chunks); n > 0 && in.chunks[n-1].offset == chunk.offset {
+		in.chunks[n-1] = chunk
+		return
+	}
+	in.chunks = append(in.chunks, chunk)
+}
+
+// displayPath names file in errors: the document's own path for "", which
+// may itself be "" for standard input.
+func (in *includer) displayPath(file string) string {
+	if file == "" {
+		return in.path
+	}
+	return file
+}
+
+// relative returns an absolute path relative to the working directory, for
+// showing include cycles.
+func (in *includer) relative(abs string) string {
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil {
+			return rel
+		}
+	}
+	return abs
+}
+
+// rewriteLinks rewrites the relative link and image destinations in a line
+// of a file in dir so that they resolve from the document.
+func (in *includer) rewriteLinks(line, dir string) string {
+	for _, re := range []*regexp.Regexp{inlineLinkDest, referenceDest, htmlLinkDest} {
+		re := re
+		line = re.ReplaceAllStringFunc(line, func(match string) string {
+			return in.rewriteMatch(re, match, dir)
+		})
+	}
+	return line
+}
+
+// rewriteMatch rewrites the destination in the second group of a match of
+// re.
+func (in *includer) rewriteMatch(re *regexp.Regexp, match, dir string) string {
+	groups := re.FindStringSubmatch(match)
+	dest := groups[2]
+	open, close := "", ""
+	switch {
+	case strings.HasPrefix(dest, "<") && strings.HasSuffix(dest, ">"):
+		open, close, dest = "<", ">", dest[1:len(dest)-1]
+	case strings.HasPrefix(dest, `"`) && strings.HasSuffix(dest, `"`) && len(dest) > 1:
+		open, close, dest = `"`, `"`, dest[1:len(dest)-1]
+	}
+	return groups[1] + open + in.rewritePath(dest, dir) + close + strings.Join(groups[3:], "")
+}
+
+// rewritePath makes a relative destination in a file in dir relative to
+// the document's directory instead. URLs, site-absolute paths and
+// fragments are returned unchanged.
+func (in *includer) rewritePath(dest, dir string) string {
+	u, err := url.Parse(dest)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return dest
+	}
+	pathEnd := strings.IndexAny(dest, "?#")
+	if pathEnd == -1 {
+		pathEnd = len(dest)
+	}
+	rel, err := filepath.Rel(in.rootDir, filepath.Join(dir, filepath.FromSlash(dest[:pathEnd])))
+	if err != nil {
+		return dest
+	}
+	return filepath.ToSlash(rel) + dest[pathEnd:]
+}
+
+// shiftHeading moves an ATX heading down by shift levels, to at most
+// level 6.
+func shiftHeading(line string, shift int) string {
+	match := atxHeading.FindStringSubmatchIndex(line)
+	if match == nil {
+		return line
+	}
+	level := min(match[5]-match[4]+shift, 6)
+	return line[:match[4]] + strings.Repeat("#", level) + line[match[5]:]
+}
+
+// stripFrontmatter returns content without its YAML frontmatter, and the
+// line the rest starts on.
+func stripFrontmatter(content []byte) ([]byte, int) {
+	if !bytes.HasPrefix(content, []byte("---\n")) && !bytes.HasPrefix(content, []byte("---\r\n")) {
+		return content, 1
+	}
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	for i := 1; i < len(lines); i++ {
+		if string(bytes.TrimRight(lines[i], "\r\n")) == "---" {
+			return bytes.Join(lines[i+1:], nil), i + 2
+		}
+	}
+	return content, 1
+}
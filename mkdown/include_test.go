@@ -0,0 +1,190 @@
+package mkdown
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIncludeFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"doc.md": "---\ntitle: Spec\n---\n# Spec\n\n{{< include \"sections/intro.md\" shift=1 >}}\n\n```md\n!include sections/intro.md\n```\n\n!include sections/usage.md\n",
+		"sections/intro.md": "---\ntitle: Intro\n---\n# Intro\n\n![logo](img/logo.png) [spec](../doc.md#spec) [web](https://example.com) [top](#intro)\n\n" +
+			"[ref]: img/ref.png\n\n<img src=\"img/raw.png\">\n\n```go {bogus=1}\nx := 1\n```\n",
+		"sections/usage.md":        "## Usage\n\n!include nested/steps.md\n",
+		"sections/nested/steps.md": "### Steps\n\n```text file=../code.txt\n```\n",
+		"sections/code.txt":        "included code\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	docPath := filepath.Join(tmpDir, "doc.md")
+
+	var warnings []string
+	c := NewConverterWithOptions(ConverterOptions{Includes: true, WarningHandler: func(path, message string) {
+		warnings = append(warnings, message)
+	}})
+	page, err := c.RenderFile(docPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	output := string(page)
+
+	for _, want := range []string{
+		`<title>Spec</title>`,
+		`<h2 id="intro">Intro</h2>`,
+		`<img src="sections/img/logo.png" alt="logo" />`,
+		`<a href="doc.md#spec">spec</a>`,
+		`<a href="https://example.com">web</a>`,
+		`<a href="#intro">top</a>`,
+		`<img src="sections/img/raw.png">`,
+		`<h2 id="usage">Usage</h2>`,
+		`<h3 id="steps">Steps</h3>`,
+		"included code",
+		"!include sections/intro.md\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if strings.Contains(output, "title: Intro") {
+		t.Error("expected the included file's frontmatter to be dropped")
+	}
+	if want := "code block (" + filepath.Join(tmpDir, "sections", "intro.md") + ":12): unknown attribute 'bogus'"; len(warnings) != 1 || !strings.HasPrefix(warnings[0], want) {
+		t.Errorf("expected a warning starting with %q, got %v", want, warnings)
+	}
+
+	deps, err := c.Dependencies(docPath)
+	if err != nil {
+		t.Fatalf("Dependencies failed: %v", err)
+	}
+	for _, name := range []string{"sections/intro.md", "sections/usage.md", "sections/nested/steps.md", "sections/code.txt"} {
+		if !slices.Contains(deps, filepath.Join(tmpDir, filepath.FromSlash(name))) {
+			t.Errorf("expected %s among the dependencies %v", name, deps)
+		}
+	}
+
+	errorTests := []struct {
+		name, content string
+		want          string
+	}{
+		{"missing.md", "# Doc\n\n!include nowhere.md\n", "missing.md:3: cannot include nowhere.md: no such file or directory"},
+		{"cycle.md", "!include sections/loop.md\n", "sections/loop.md:2: include cycle: "},
+		{"option.md", "{{< include \"sections/code.txt\" depth=2 >}}\n", "option.md:1: unknown include option 'depth'"},
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "sections", "loop.md"), []byte("text\n!include ../cycle.md\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range errorTests {
+		path := filepath.Join(tmpDir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.RenderFile(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestIncludeConfinement(t *testing.T) {
+	tmpDir := t.TempDir()
+	docs := filepath.Join(tmpDir, "docs")
+	files := map[string]string{
+		"docs/part.md":     "Part text.\n",
+		"docs/sub/up.md":   "!include ../part.md\n",
+		"secret.md":        "Secret text.\n",
+		"docs/sub/leak.md": "!include ../../secret.md\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(tmpDir, "secret.md"), filepath.Join(docs, "link.md")); err != nil {
+		t.Fatal(err)
+	}
+	render := func(c *Converter, name, markdown string) (string, error) {
+		t.Helper()
+		path := filepath.Join(docs, name)
+		if err := os.WriteFile(path, []byte(markdown), 0644); err != nil {
+			t.Fatal(err)
+		}
+		page, err := c.RenderFile(path)
+		return string(page), err
+	}
+
+	// Includes are off unless enabled.
+	page, err := render(New(), "doc.md", "!include part.md\n")
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if strings.Contains(page, "Part text.") || !strings.Contains(page, "<p>!include part.md</p>") {
+		t.Error("expected the directive to stay text by default")
+	}
+
+	c := New(WithIncludes(true))
+	page, err = render(c, "doc.md", "!include sub/up.md\n")
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(page, "Part text.") {
+		t.Error("expected a nested include inside the root to work")
+	}
+
+	errorTests := []struct {
+		name, markdown, want string
+	}{
+		{"abs.md", "!include " + filepath.Join(tmpDir, "secret.md") + "\n", "abs.md:1: cannot include " + filepath.Join(tmpDir, "secret.md") + ": absolute paths are not allowed"},
+		{"escape.md", "!include ../secret.md\n", "escape.md:1: cannot include ../secret.md: outside " + docs},
+		{"nested.md", "!include sub/leak.md\n", "leak.md:1: cannot include ../../secret.md: outside " + docs},
+		{"symlink.md", "!include link.md\n", "symlink.md:1: cannot include link.md: outside " + docs},
+	}
+	for _, tt := range errorTests {
+		if _, err := render(c, tt.name, tt.markdown); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// A wider root lets documents include files next to their directory.
+	page, err = render(New(WithIncludes(true), WithIncludeRoot(tmpDir)), "wide.md", "!include ../secret.md\n")
+	if err != nil || !strings.Contains(page, "Secret text.") {
+		t.Errorf("expected the include inside the root to work, got %v", err)
+	}
+
+	// Input that did not come from a file needs a root, which paths are
+	// then relative to.
+	if _, err := c.ConvertBytes([]byte("!include part.md\n")); err == nil || !strings.Contains(err.Error(), "include root") {
+		t.Errorf("expected byte input to need an include root, got %v", err)
+	}
+	output, err := New(WithIncludes(true), WithIncludeRoot(docs)).ConvertBytes([]byte("!include part.md\n"))
+	if err != nil || !strings.Contains(string(output), "Part text.") {
+		t.Errorf("expected byte input to include from the root, got %v", err)
+	}
+}
+
+func TestShiftHeading(t *testing.T) {
+	tests := map[string]string{
+		"# Title\n":     "### Title\n",
+		"##### Deep\n":  "###### Deep\n",
+		"#hashtag\n":    "#hashtag\n",
+		"Text # here\n": "Text # here\n",
+	}
+	for line, want := range tests {
+		if got := shiftHeading(line, 2); got != want {
+			t.Errorf("shiftHeading(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
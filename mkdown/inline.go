@@ -0,0 +1,137 @@
+package mkdown
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// DefaultMaxInlineSize is the largest file inlined as a data URI when
+// ConverterOptions.MaxInlineSize is not set.
+const DefaultMaxInlineSize = 5 << 20
+
+// inlineImages replaces the destinations of local images below root with
+// data URIs. Images that cannot be inlined keep their path and are
+// described in the returned warnings.
+func inlineImages(root ast.Node, baseDir string, maxSize int64) []string {
+	var warnings []string
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		img, ok := n.(*ast.Image)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		dest := string(img.Destination)
+		path, ok := localPath(baseDir, dest)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+
+		uri, err := fileDataURI(path, maxSize)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("image '%s' not inlined: %v", dest, err))
+			return ast.WalkContinue, nil
+		}
+		img.Destination = []byte(uri)
+		return ast.WalkContinue, nil
+	})
+	return warnings
+}
+
+// fileDataURI reads an image no larger than maxSize and encodes it as a data
+// URI. The MIME type is sniffed from the contents, falling back to the file
+// extension for formats such as SVG that cannot be sniffed reliably.
+func fileDataURI(path string, maxSize int64) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("file not found")
+	}
+	if info.Size() > maxSize {
+		return "", fmt.Errorf("%s exceeds the %s limit", formatSize(info.Size()), formatSize(maxSize))
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	mimeType := http.DetectContentType(data)
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return "", fmt.Errorf("not a recognised image format")
+	}
+	if i := strings.IndexByte(mimeType, ';'); i != -1 {
+		mimeType = mimeType[:i]
+	}
+	return dataURI(mimeType, data), nil
+}
+
+// formatSize formats a byte count for messages, such as "1.5 MB".
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+// stylesheetLink matches <link> elements, and linkAttr their attributes.
+var (
+	stylesheetLink = regexp.MustCompile(`(?i)<link\b[^>]*>`)
+	linkAttr       = regexp.MustCompile(`(?i)\b(rel|href)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+)
+
+// inlineStylesheets replaces <link rel="stylesheet"> elements in page that
+// point at local files with <style> elements holding the file contents.
+// Relative paths are resolved against baseDir.
+func inlineStylesheets(page []byte, baseDir string, maxSize int64) ([]byte, []string) {
+	var warnings []string
+	page = stylesheetLink.ReplaceAllFunc(page, func(tag []byte) []byte {
+		var rel, href string
+		for _, m := range linkAttr.FindAllSubmatch(tag, -1) {
+			value := string(m[2]) + string(m[3]) + string(m[4])
+			if strings.EqualFold(string(m[1]), "rel") {
+				rel = value
+			} else {
+				href = value
+			}
+		}
+		if !strings.EqualFold(strings.TrimSpace(rel), "stylesheet") {
+			return tag
+		}
+		path, ok := localPath(baseDir, href)
+		if !ok {
+			return tag
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("stylesheet '%s' not inlined: file not found", href))
+			return tag
+		}
+		if info.Size() > maxSize {
+			warnings = append(warnings, fmt.Sprintf("stylesheet '%s' not inlined: %s exceeds the %s limit", href, formatSize(info.Size()), formatSize(maxSize)))
+			return tag
+		}
+		css, err := os.ReadFile(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("stylesheet '%s' not inlined: %v", href, err))
+			return tag
+		}
+
+		// A stylesheet cannot contain "</style" without ending the element.
+		css = bytes.ReplaceAll(css, []byte("</style"), []byte(`<\/style`))
+		return append(append([]byte("<style>\n"), css...), "\n</style>"...)
+	})
+	return page, warnings
+}
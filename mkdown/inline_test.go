@@ -0,0 +1,119 @@
+package mkdown
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInlineImages(t *testing.T) {
+	tmpDir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	files := map[string][]byte{
+		"img/logo.png":     png,
+		"img/mislabel.gif": png,
+		"diagram.svg":      []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`),
+		"big.png":          append(append([]byte{}, png...), make([]byte, 100)...),
+		"notes.txt":        []byte("plain text"),
+	}
+	for name, data := range files {
+		path := filepath.Join(tmpDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inputPath := filepath.Join(tmpDir, "doc.md")
+	source := "![logo](img/logo.png) ![m](img/mislabel.gif) ![d](diagram.svg) ![big](big.png) ![gone](missing.png) ![t](notes.txt) ![remote](https://example.com/x.png)"
+	if err := os.WriteFile(inputPath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings []string
+	c := New(
+		WithInlineImages(true),
+		WithMaxInlineSize(64),
+		WithWarningHandler(func(path, message string) {
+			warnings = append(warnings, message)
+		}),
+	)
+	output, err := c.RenderFile(inputPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+
+	outputStr := string(output)
+	pngURI := "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	for _, want := range []string{
+		`<img src="` + pngURI + `" alt="logo"`,
+		`<img src="` + pngURI + `" alt="m"`, // sniffed, not taken from .gif
+		`<img src="data:image/svg+xml;base64,`,
+		`<img src="big.png"`,
+		`<img src="missing.png"`,
+		`<img src="notes.txt"`,
+		`<img src="https://example.com/x.png"`,
+	} {
+		if !strings.Contains(outputStr, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	want := []string{
+		"image 'big.png' not inlined: 116 bytes exceeds the 64 bytes limit",
+		"image 'missing.png' not inlined: file not found",
+		"image 'notes.txt' not inlined: not a recognised image format",
+	}
+	if fmt.Sprint(warnings) != fmt.Sprint(want) {
+		t.Errorf("expected warnings %q, got %q", want, warnings)
+	}
+}
+
+func TestInlineCSS(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeFile := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	writeFile("site.css", "body { color: teal; }")
+	writeFile("doc.css", "h1 { color: plum; }")
+	templatePath := writeFile("page.html", `<html><head><link rel="stylesheet" href="site.css"><link rel="icon" href="site.css"><link rel="stylesheet" href="https://example.com/x.css"></head><body>{{ .Content }}</body></html>`)
+	inputPath := writeFile("doc.md", "<link rel='stylesheet' href='doc.css'>\n<link rel=stylesheet href=absent.css>\n\n# Styled")
+
+	var warnings []string
+	c := New(
+		WithTemplate(templatePath),
+		WithInlineCSS(true),
+		WithWarningHandler(func(path, message string) {
+			warnings = append(warnings, message)
+		}),
+	)
+	output, err := c.RenderFile(inputPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+
+	outputStr := string(output)
+	for _, want := range []string{
+		"<style>\nbody { color: teal; }\n</style>",
+		"<style>\nh1 { color: plum; }\n</style>",
+		`<link rel="icon" href="site.css">`,
+		`<link rel="stylesheet" href="https://example.com/x.css">`,
+		`<link rel=stylesheet href=absent.css>`,
+	} {
+		if !strings.Contains(outputStr, want) {
+			t.Errorf("expected %q in output:\n%s", want, outputStr)
+		}
+	}
+	if len(warnings) != 1 || warnings[0] != "stylesheet 'absent.css' not inlined: file not found" {
+		t.Errorf("unexpected warnings: %q", warnings)
+	}
+}
@@ -0,0 +1,98 @@
+package mkdown
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// pageLinker makes links from the page being generated to the pages
+// generated from other markdown files.
+type pageLinker struct {
+	fromDir  string // absolute directory of the page being generated
+	outputOf func(path string) string
+}
+
+// newPageLinker returns a pageLinker for the page generated from the
+// markdown file at path and written to output. An empty output means the
+// page goes where outputOf says, or next to path.
+func (c *Converter) newPageLinker(path, output string) (*pageLinker, error) {
+	l := &pageLinker{outputOf: c.outputPath}
+	if output == "" {
+		output = l.htmlPath(path)
+		if path == "" {
+			output = "document.html"
+		}
+	}
+	fromDir, err := filepath.Abs(filepath.Dir(output))
+	if err != nil {
+		return nil, err
+	}
+	l.fromDir = fromDir
+	return l, nil
+}
+
+// htmlPath returns the HTML file generated from the markdown file at path:
+// the one outputOf names, or the file next to path with an .html
+// extension.
+func (l *pageLinker) htmlPath(path string) string {
+	if l.outputOf != nil {
+		if output := l.outputOf(path); output != "" {
+			return output
+		}
+	}
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".html"
+}
+
+// link returns the relative URL of the page generated from the markdown
+// file at path.
+func (l *pageLinker) link(path string) (string, error) {
+	target, err := filepath.Abs(l.htmlPath(path))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(l.fromDir, target)
+	if err != nil {
+		return "", err
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/"), nil
+}
+
+// rewriteMarkdownLinks points links to local markdown files, such as
+// [setup](setup.md#install), at the HTML pages generated from them,
+// keeping any query and fragment. path is the document, which link
+// destinations are relative to. URLs, site-absolute paths, fragments and
+// links to other files are left alone.
+func rewriteMarkdownLinks(root ast.Node, path string, linker *pageLinker) error {
+	return ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		dest := string(link.Destination)
+		u, err := url.Parse(dest)
+		if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+			return ast.WalkContinue, nil
+		}
+		if ext := strings.ToLower(filepath.Ext(u.Path)); ext != ".md" && ext != ".markdown" {
+			return ast.WalkContinue, nil
+		}
+
+		rewritten, err := linker.link(filepath.Join(baseDir(path), filepath.FromSlash(u.Path)))
+		if err != nil {
+			return ast.WalkStop, err
+		}
+		if suffix := strings.IndexAny(dest, "?#"); suffix != -1 {
+			rewritten += dest[suffix:]
+		}
+		link.Destination = []byte(rewritten)
+		return ast.WalkContinue, nil
+	})
+}
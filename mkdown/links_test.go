@@ -0,0 +1,90 @@
+package mkdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRewriteLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	docPath := filepath.Join(tmpDir, "docs", "guide.md")
+	if err := os.MkdirAll(filepath.Dir(docPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	input := "# Guide\n\n[setup](setup.md#install) [ref][r] [up](../README.MD?x=1) [my page](my%20page.markdown) " +
+		"[self](guide.md) [web](https://example.com/a.md) [abs](/docs/a.md) [anchor](#guide) [text](notes.txt) ![img](pic.md.png)\n\n" +
+		"[r]: sub/ref.md\n"
+	if err := os.WriteFile(docPath, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outputs := map[string]string{
+		filepath.Join(tmpDir, "docs", "guide.md"): filepath.Join(tmpDir, "site", "guide.html"),
+		filepath.Join(tmpDir, "docs", "setup.md"): filepath.Join(tmpDir, "site", "setup.html"),
+	}
+	c := NewConverterWithOptions(ConverterOptions{
+		RewriteLinks: true,
+		OutputPath:   func(path string) string { return outputs[path] },
+	})
+	outPath := filepath.Join(tmpDir, "site", "guide.html")
+	if err := c.Convert(docPath, outPath); err != nil {
+		t.Fatalf("Convert failed: %v", err)
+	}
+	page, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := string(page)
+
+	for _, want := range []string{
+		`<a href="setup.html#install">setup</a>`,
+		`<a href="../docs/sub/ref.html">ref</a>`,
+		`<a href="../README.html?x=1">up</a>`,
+		`<a href="../docs/my%20page.html">my page</a>`,
+		`<a href="guide.html">self</a>`,
+		`<a href="https://example.com/a.md">web</a>`,
+		`<a href="/docs/a.md">abs</a>`,
+		`<a href="#guide">anchor</a>`,
+		`<a href="notes.txt">text</a>`,
+		`<img src="pic.md.png" alt="img" />`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+
+	// Without output paths the pages are next to their markdown.
+	page, err = NewConverterWithOptions(ConverterOptions{RewriteLinks: true}).RenderFile(docPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<a href="sub/ref.html">ref</a>`) || !strings.Contains(string(page), `<a href="../README.html?x=1">up</a>`) {
+		t.Errorf("expected links next to the markdown files:\n%s", page)
+	}
+
+	// New rewrites links by default, like the CLI, and leaves them alone
+	// when disabled.
+	page, err = New(WithOutputPath(func(path string) string { return outputs[path] })).RenderFile(docPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<a href="setup.html#install">setup</a>`) {
+		t.Errorf("expected links to be rewritten by default:\n%s", page)
+	}
+	page, err = New(WithRewriteLinks(false)).RenderFile(docPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<a href="setup.md#install">setup</a>`) {
+		t.Error("expected links to stay unchanged when disabled")
+	}
+	page, err = NewConverterWithOptions(ConverterOptions{}).RenderFile(docPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<a href="setup.md#install">setup</a>`) {
+		t.Error("expected links to stay unchanged unless RewriteLinks is set")
+	}
+}
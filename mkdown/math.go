@@ -0,0 +1,413 @@
+package mkdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindMathBlock is the NodeKind of MathBlock nodes.
+var KindMathBlock = ast.NewNodeKind("MathBlock")
+
+// KindMathInline is the NodeKind of MathInline nodes.
+var KindMathInline = ast.NewNodeKind("MathInline")
+
+// MathBlock is display math written on its own lines between $$ or \[ and
+// \] delimiters. Its lines hold the TeX source without the delimiters.
+type MathBlock struct {
+	ast.BaseBlock
+	closed bool
+	mathml string
+}
+
+// Kind implements ast.Node.Kind.
+func (n *MathBlock) Kind() ast.NodeKind {
+	return KindMathBlock
+}
+
+// IsRaw implements ast.Node.IsRaw. Math is never parsed as markdown.
+func (n *MathBlock) IsRaw() bool {
+	return true
+}
+
+// Dump implements ast.Node.Dump.
+func (n *MathBlock) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// MathInline is math inside a paragraph: $...$ and \(...\) for inline math,
+// or $$...$$ and \[...\] for display math. Its children are raw text
+// segments holding the TeX source.
+type MathInline struct {
+	ast.BaseInline
+	Display bool
+	mathml  string
+}
+
+// Kind implements ast.Node.Kind.
+func (n *MathInline) Kind() ast.NodeKind {
+	return KindMathInline
+}
+
+// Dump implements ast.Node.Dump.
+func (n *MathInline) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Display": boolString(n.Display)}, nil)
+}
+
+func boolString(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// mathText returns the TeX source of a math node.
+func mathText(n ast.Node, source []byte) []byte {
+	var buf bytes.Buffer
+	if _, ok := n.(*MathBlock); ok {
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			line := lines.At(i)
+			buf.Write(line.Value(source))
+		}
+		return bytes.TrimSpace(buf.Bytes())
+	}
+
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if t, ok := c.(*ast.Text); ok {
+			buf.Write(t.Segment.Value(source))
+		}
+	}
+	return bytes.TrimSpace(buf.Bytes())
+}
+
+// mathBlockParser parses display math blocks that start a line with $$ or
+// \[, the same way fenced code blocks start with ```.
+type mathBlockParser struct{}
+
+func (b *mathBlockParser) Trigger() []byte {
+	return []byte{'$', '\\'}
+}
+
+func (b *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || pos+2 > len(line) {
+		return nil, parser.NoChildren
+	}
+
+	var closer []byte
+	switch string(line[pos : pos+2]) {
+	case "$$":
+		closer = []byte("$$")
+	case `\[`:
+		closer = []byte(`\]`)
+	default:
+		return nil, parser.NoChildren
+	}
+
+	node := &MathBlock{}
+	rest := line[pos+2:]
+	start := segment.Start + pos + 2
+
+	if idx := bytes.Index(rest, closer); idx != -1 {
+		// Single-line block such as "$$ x^2 $$". Anything after the closing
+		// delimiter means this is inline math inside a paragraph instead.
+		if !util.IsBlank(rest[idx+len(closer):]) {
+			return nil, parser.NoChildren
+		}
+		node.Lines().Append(text.NewSegment(start, start+idx))
+		node.closed = true
+	} else if !util.IsBlank(rest) {
+		node.Lines().Append(text.NewSegment(start, segment.Stop))
+	}
+
+	pc.Set(mathBlockCloserKey, closer)
+	advanceLine(reader, line, segment)
+	return node, parser.NoChildren
+}
+
+func (b *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if node.(*MathBlock).closed {
+		return parser.Close
+	}
+
+	line, segment := reader.PeekLine()
+	closer, _ := pc.Get(mathBlockCloserKey).([]byte)
+
+	trimmed := util.TrimRightSpace(line)
+	if bytes.HasSuffix(trimmed, closer) {
+		content := len(trimmed) - len(closer)
+		if !util.IsBlank(line[:content]) {
+			node.Lines().Append(text.NewSegment(segment.Start, segment.Start+content))
+		}
+		advanceLine(reader, line, segment)
+		return parser.Close
+	}
+
+	node.Lines().Append(segment)
+	advanceLine(reader, line, segment)
+	return parser.Continue | parser.NoChildren
+}
+
+func (b *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {
+	pc.Set(mathBlockCloserKey, nil)
+}
+
+func (b *mathBlockParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (b *mathBlockParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+var mathBlockCloserKey = parser.NewContextKey()
+
+// advanceLine moves reader to the end of line, leaving the newline for the
+// block parser as goldmark expects. The last line of a file may have none.
+func advanceLine(reader text.Reader, line []byte, segment text.Segment) {
+	n := segment.Len()
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		n--
+	}
+	reader.Advance(n)
+}
+
+// mathInlineParser parses math inside paragraphs. Code spans are parsed
+// before it gets a chance to see their contents, and a backslash-escaped \$
+// never opens or closes math.
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte {
+	return []byte{'$', '\\'}
+}
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if len(line) < 2 {
+		return nil
+	}
+
+	switch {
+	case line[0] == '\\' && line[1] == '(':
+		return parseDelimitedMath(block, 2, []byte(`\)`), false)
+	case line[0] == '\\' && line[1] == '[':
+		return parseDelimitedMath(block, 2, []byte(`\]`), true)
+	case line[0] == '$' && line[1] == '$':
+		return parseDelimitedMath(block, 2, []byte("$$"), true)
+	case line[0] == '$':
+		return parseDollarMath(block)
+	}
+	return nil
+}
+
+// parseDollarMath parses $...$ on a single line using the pandoc rules: the
+// opening $ must be followed by a non-space, the closing $ must follow a
+// non-space and must not be followed by a digit. This keeps prices such as
+// "$5 and $10" as text.
+func parseDollarMath(block text.Reader) ast.Node {
+	line, segment := block.PeekLine()
+	if util.IsSpace(line[1]) {
+		return nil
+	}
+
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip escaped character
+		case '`':
+			// A code span takes precedence over math, so a $ inside one
+			// cannot close it.
+			i = codeSpanEnd(line, i) - 1
+		case '$':
+			if util.IsSpace(line[i-1]) || (i+1 < len(line) && util.IsNumeric(line[i+1])) {
+				continue
+			}
+			node := &MathInline{}
+			node.AppendChild(node, ast.NewRawTextSegment(text.NewSegment(segment.Start+1, segment.Start+i)))
+			block.Advance(i + 1)
+			return node
+		}
+	}
+	return nil
+}
+
+// codeSpanEnd returns the index after the code span opened by the run of
+// backticks at line[start]. If no run of the same length closes it, the
+// backticks are literal and the index after them is returned.
+func codeSpanEnd(line []byte, start int) int {
+	n := 0
+	for start+n < len(line) && line[start+n] == '`' {
+		n++
+	}
+	for i := start + n; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		run := 0
+		for i+run < len(line) && line[i+run] == '`' {
+			run++
+		}
+		if run == n {
+			return i + run
+		}
+		i += run
+	}
+	return start + n
+}
+
+// mathCloserIndex returns the index of closer in line, or -1. Closers
+// inside code spans or escaped with a backslash do not count.
+func mathCloserIndex(line, closer []byte) int {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case bytes.HasPrefix(line[i:], closer):
+			return i
+		case line[i] == '\\':
+			i++ // skip escaped character
+		case line[i] == '`':
+			i = codeSpanEnd(line, i) - 1
+		}
+	}
+	return -1
+}
+
+// parseDelimitedMath parses math that starts with an opener of openerLen
+// bytes and runs, possibly over several lines of the paragraph, until closer.
+func parseDelimitedMath(block text.Reader, openerLen int, closer []byte, display bool) ast.Node {
+	l, pos := block.Position()
+	block.Advance(openerLen)
+
+	node := &MathInline{Display: display}
+	for {
+		line, segment := block.PeekLine()
+		if line == nil {
+			block.SetPosition(l, pos)
+			return nil
+		}
+
+		if idx := mathCloserIndex(line, closer); idx != -1 {
+			if idx > 0 {
+				node.AppendChild(node, ast.NewRawTextSegment(segment.WithStop(segment.Start+idx)))
+			}
+			block.Advance(idx + len(closer))
+			if !node.HasChildren() {
+				block.SetPosition(l, pos)
+				return nil
+			}
+			return node
+		}
+
+		node.AppendChild(node, ast.NewRawTextSegment(segment))
+		block.AdvanceLine()
+	}
+}
+
+// mathHTMLRenderer renders math nodes as elements holding the escaped TeX
+// source, which the KaTeX loader script typesets in the browser, or holding
+// the MathML produced by convertMathML.
+type mathHTMLRenderer struct{}
+
+func (r *mathHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindMathBlock, r.renderMathBlock)
+	reg.Register(KindMathInline, r.renderMathInline)
+}
+
+func (r *mathHTMLRenderer) renderMathBlock(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		_, _ = w.WriteString(`<div class="math math-display">`)
+		writeMath(w, source, n, n.(*MathBlock).mathml)
+		_, _ = w.WriteString("</div>\n")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func (r *mathHTMLRenderer) renderMathInline(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if entering {
+		node := n.(*MathInline)
+		class := "math math-inline"
+		if node.Display {
+			class = "math math-display"
+		}
+		_, _ = w.WriteString(`<span class="` + class + `">`)
+		writeMath(w, source, n, node.mathml)
+		_, _ = w.WriteString("</span>")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+func writeMath(w util.BufWriter, source []byte, n ast.Node, mathml string) {
+	if mathml != "" {
+		_, _ = w.WriteString(mathml)
+		return
+	}
+	_, _ = w.Write(util.EscapeHTML(mathText(n, source)))
+}
+
+// unsupportedMath is a LaTeX command the MathML converter could not handle.
+type unsupportedMath struct {
+	command string
+	offset  int // byte offset of the math in the source
+}
+
+// convertMathML converts every math node below root to MathML at build time
+// and returns the commands it could not convert.
+func convertMathML(root ast.Node, source []byte) []unsupportedMath {
+	var unsupported []unsupportedMath
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		var commands []string
+		switch node := n.(type) {
+		case *MathBlock:
+			node.mathml, commands = texToMathML(string(mathText(n, source)), true)
+		case *MathInline:
+			node.mathml, commands = texToMathML(string(mathText(n, source)), node.Display)
+		default:
+			return ast.WalkContinue, nil
+		}
+
+		for _, command := range commands {
+			unsupported = append(unsupported, unsupportedMath{command, mathOffset(n)})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return unsupported
+}
+
+// mathOffset returns the byte offset where the TeX source of n starts.
+func mathOffset(n ast.Node) int {
+	// Inline nodes have no lines; their source is in their text children.
+	if _, ok := n.(*MathInline); ok {
+		if t, ok := n.FirstChild().(*ast.Text); ok {
+			return t.Segment.Start
+		}
+		return 0
+	}
+	if lines := n.Lines(); lines.Len() > 0 {
+		return lines.At(0).Start
+	}
+	return 0
+}
+
+// mathExtension adds $...$, $$...$$, \(...\) and \[...\] math to goldmark.
+type mathExtension struct{}
+
+func (e *mathExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(util.Prioritized(&mathBlockParser{}, 701)),
+		parser.WithInlineParsers(util.Prioritized(&mathInlineParser{}, 500)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&mathHTMLRenderer{}, 500),
+	))
+}
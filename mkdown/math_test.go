@@ -0,0 +1,192 @@
+package mkdown
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestConcurrentMathConversion renders many math documents in parallel with
+// shared and separate converters. Run with -race to check for data races.
+func TestConcurrentMathConversion(t *testing.T) {
+	converters := []*Converter{
+		NewConverterWithOptions(ConverterOptions{Theme: "dark", EnableMath: true}),
+		NewConverterWithOptions(ConverterOptions{Theme: "light", EnableMath: true}),
+	}
+
+	const perConverter = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, len(converters)*perConverter)
+
+	for ci, c := range converters {
+		for i := 0; i < perConverter; i++ {
+			wg.Add(1)
+			go func(c *Converter, id string) {
+				defer wg.Done()
+
+				source := fmt.Sprintf("# Doc %s\n\n$$\nx_{%s} = %s\n$$\n\nand\n\n$$y_{%s}$$\n", id, id, id, id)
+				output, err := c.ConvertBytes([]byte(source))
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				for _, want := range []string{"x_{" + id + "} = " + id, "y_{" + id + "}"} {
+					if !strings.Contains(string(output), want) {
+						errs <- fmt.Errorf("document %s: expected math %q in output", id, want)
+					}
+				}
+				if strings.Contains(string(output), "MATH_BLOCK") {
+					errs <- fmt.Errorf("document %s: unrestored math placeholder", id)
+				}
+			}(c, fmt.Sprintf("%d_%d", ci, i))
+		}
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestMathParsing(t *testing.T) {
+	c := NewConverterWithOptions(ConverterOptions{Theme: "dark", EnableMath: true})
+
+	tests := []struct {
+		name     string
+		input    string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "display block",
+			input:    "$$\n\\sum_{i=1}^n i\n$$",
+			contains: []string{`<div class="math math-display">\sum_{i=1}^n i</div>`},
+		},
+		{
+			name:     "bracket display block",
+			input:    "\\[\na^2 + b^2\n\\]",
+			contains: []string{`<div class="math math-display">a^2 + b^2</div>`},
+		},
+		{
+			name:     "inline dollars keep emphasis characters",
+			input:    "Product $a*b*c$ here",
+			contains: []string{`<span class="math math-inline">a*b*c</span>`},
+			excludes: []string{"<em>"},
+		},
+		{
+			name:     "inline parentheses",
+			input:    "Inline \\(x_1 + x_2\\) math",
+			contains: []string{`<span class="math math-inline">x_1 + x_2</span>`},
+		},
+		{
+			name:     "inline display dollars",
+			input:    "See $$E = mc^2$$ inline",
+			contains: []string{`<span class="math math-display">E = mc^2</span>`},
+		},
+		{
+			name:     "prices stay text",
+			input:    "It costs $5 and $10 today",
+			contains: []string{"It costs $5 and $10 today"},
+			excludes: []string{"math"},
+		},
+		{
+			name:     "escaped dollar",
+			input:    "Pay \\$x$ now",
+			contains: []string{"Pay $x$ now"},
+			excludes: []string{"math-inline"},
+		},
+		{
+			name:     "fenced code is untouched",
+			input:    "```bash\necho $$\necho $HOME\n```",
+			contains: []string{"echo"},
+			excludes: []string{"math-display", "math-inline"},
+		},
+		{
+			name:     "code span is untouched",
+			input:    "Run `echo $$` to print the PID",
+			contains: []string{"<code>echo $$</code>"},
+			excludes: []string{"math"},
+		},
+		{
+			name:     "display block ending the file",
+			input:    "Before\n\n$$\nx\n$$",
+			contains: []string{`<div class="math math-display">x</div>`},
+			excludes: []string{"<p>$</p>"},
+		},
+		{
+			name:     "bracket block ending the file",
+			input:    "\\[\nx\n\\]",
+			contains: []string{`<div class="math math-display">x</div>`},
+			excludes: []string{"<p>]</p>", "<p>\\</p>"},
+		},
+		{
+			name:     "code span inside dollars",
+			input:    "A $x `code$` b",
+			contains: []string{"A $x <code>code$</code> b"},
+			excludes: []string{"math"},
+		},
+		{
+			name:     "code span inside display dollars",
+			input:    "PID is $$ in bash and `$$` too.",
+			contains: []string{"PID is $$ in bash and <code>$$</code> too."},
+			excludes: []string{"math"},
+		},
+		{
+			name:     "code span inside parentheses",
+			input:    "A \\(x `\\)` y",
+			contains: []string{"<code>\\)</code> y"},
+			excludes: []string{"math"},
+		},
+		{
+			name:     "escaped dollar inside display math",
+			input:    "Cost $$a \\$$ b$$ here.",
+			contains: []string{`<span class="math math-display">a \$$ b</span> here.`},
+		},
+		{
+			name:     "math around a lone backtick",
+			input:    "A $x`y$ b",
+			contains: []string{`<span class="math math-inline">x` + "`" + `y</span>`},
+		},
+		{
+			name:     "tex is escaped",
+			input:    "$a<b$",
+			contains: []string{`<span class="math math-inline">a&lt;b</span>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := c.RenderDocument(context.Background(), []byte(tt.input))
+			if err != nil {
+				t.Fatalf("RenderDocument failed: %v", err)
+			}
+			content := string(doc.Content)
+			for _, want := range tt.contains {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %q in:\n%s", want, content)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(content, unwanted) {
+					t.Errorf("did not expect %q in:\n%s", unwanted, content)
+				}
+			}
+		})
+	}
+}
+
+func TestMathDisabledLeavesDollarsAlone(t *testing.T) {
+	c := NewConverter("dark")
+
+	doc, err := c.RenderDocument(context.Background(), []byte("$$\nx\n$$"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if strings.Contains(string(doc.Content), "math") || len(doc.RequiredScripts) != 0 {
+		t.Errorf("math should not be parsed when disabled: %s", doc.Content)
+	}
+}
@@ -0,0 +1,859 @@
+package mkdown
+
+import (
+	"strings"
+	"unicode"
+)
+
+// mmlNode is an element of the MathML tree built from TeX source.
+type mmlNode struct {
+	tag      string
+	attrs    [][2]string
+	text     string
+	children []*mmlNode
+
+	// limits places sub- and superscripts under and over the node in display
+	// math, as for \sum and \lim.
+	limits bool
+	// function marks operator names such as \sin, which are followed by a
+	// thin space unless an opening delimiter comes next.
+	function bool
+}
+
+func newMML(tag string, children ...*mmlNode) *mmlNode {
+	return &mmlNode{tag: tag, children: children}
+}
+
+func newMMLText(tag, text string) *mmlNode {
+	return &mmlNode{tag: tag, text: text}
+}
+
+func (n *mmlNode) attr(name, value string) *mmlNode {
+	n.attrs = append(n.attrs, [2]string{name, value})
+	return n
+}
+
+var mmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func (n *mmlNode) write(b *strings.Builder) {
+	b.WriteString("<" + n.tag)
+	for _, a := range n.attrs {
+		b.WriteString(" " + a[0] + `="` + mmlEscaper.Replace(a[1]) + `"`)
+	}
+	b.WriteString(">")
+	b.WriteString(mmlEscaper.Replace(n.text))
+	for _, c := range n.children {
+		c.write(b)
+	}
+	b.WriteString("</" + n.tag + ">")
+}
+
+// mrow groups nodes, avoiding a wrapper around a single node.
+func mrow(nodes []*mmlNode) *mmlNode {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return newMML("mrow", nodes...)
+}
+
+func mspace(width string) *mmlNode {
+	return newMML("mspace").attr("width", width)
+}
+
+var texIdentifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"infty": "∞", "ell": "ℓ", "hbar": "ℏ", "partial": "∂", "nabla": "∇",
+	"emptyset": "∅", "varnothing": "∅", "aleph": "ℵ", "Re": "ℜ", "Im": "ℑ",
+	"wp": "℘", "imath": "ı", "jmath": "ȷ",
+}
+
+// texUprightIdentifiers are identifiers TeX sets upright rather than italic.
+var texUprightIdentifiers = map[string]string{
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+}
+
+var texOperators = map[string]string{
+	"pm": "±", "mp": "∓", "times": "×", "div": "÷", "cdot": "⋅", "ast": "∗",
+	"star": "⋆", "circ": "∘", "bullet": "∙", "oplus": "⊕", "ominus": "⊖",
+	"otimes": "⊗", "odot": "⊙", "cap": "∩", "cup": "∪", "setminus": "∖",
+	"wedge": "∧", "land": "∧", "vee": "∨", "lor": "∨", "neg": "¬", "lnot": "¬",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"ll": "≪", "gg": "≫", "approx": "≈", "sim": "∼", "simeq": "≃", "cong": "≅",
+	"equiv": "≡", "propto": "∝", "prec": "≺", "succ": "≻", "preceq": "⪯",
+	"succeq": "⪰", "subset": "⊂", "supset": "⊃", "subseteq": "⊆",
+	"supseteq": "⊇", "in": "∈", "notin": "∉", "ni": "∋", "mid": "∣",
+	"parallel": "∥", "perp": "⊥", "to": "→", "rightarrow": "→",
+	"leftarrow": "←", "gets": "←", "leftrightarrow": "↔", "Rightarrow": "⇒",
+	"Leftarrow": "⇐", "Leftrightarrow": "⇔", "implies": "⟹", "impliedby": "⟸",
+	"iff": "⟺", "mapsto": "↦", "longrightarrow": "⟶", "longleftarrow": "⟵",
+	"Longrightarrow": "⟹", "Longleftarrow": "⟸", "uparrow": "↑",
+	"downarrow": "↓", "forall": "∀", "exists": "∃", "nexists": "∄",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"colon": ":", "langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "vert": "|", "Vert": "‖", "lvert": "|",
+	"rvert": "|", "lVert": "‖", "rVert": "‖", "backslash": "\\", "prime": "′",
+	"angle": "∠", "triangle": "△", "therefore": "∴", "because": "∵",
+	"top": "⊤", "bot": "⊥", "dagger": "†", "ddagger": "‡", "lbrace": "{",
+	"rbrace": "}", "bmod": "mod",
+}
+
+// texLargeOperators maps big operators to their symbol and whether they take
+// limits above and below in display math.
+var texLargeOperators = map[string]struct {
+	symbol string
+	limits bool
+}{
+	"sum": {"∑", true}, "prod": {"∏", true}, "coprod": {"∐", true},
+	"bigcup": {"⋃", true}, "bigcap": {"⋂", true}, "bigoplus": {"⨁", true},
+	"bigotimes": {"⨂", true}, "bigvee": {"⋁", true}, "bigwedge": {"⋀", true},
+	"bigsqcup": {"⨆", true}, "int": {"∫", false}, "iint": {"∬", false},
+	"iiint": {"∭", false}, "oint": {"∮", false},
+}
+
+// texFunctions are operator names set in upright text; the value reports
+// whether they take limits in display math.
+var texFunctions = map[string]bool{
+	"sin": false, "cos": false, "tan": false, "cot": false, "sec": false,
+	"csc": false, "sinh": false, "cosh": false, "tanh": false, "coth": false,
+	"arcsin": false, "arccos": false, "arctan": false, "log": false,
+	"ln": false, "lg": false, "exp": false, "deg": false, "det": true,
+	"dim": false, "hom": false, "ker": false, "arg": false, "gcd": true,
+	"Pr": true, "lim": true, "liminf": true, "limsup": true, "max": true,
+	"min": true, "sup": true, "inf": true,
+}
+
+var texFunctionNames = map[string]string{"liminf": "lim inf", "limsup": "lim sup"}
+
+var texSpaces = map[string]string{
+	",": "0.1667em", ":": "0.2222em", ">": "0.2222em", ";": "0.2778em",
+	"!": "-0.1667em", " ": "0.25em", "quad": "1em", "qquad": "2em",
+	"enspace": "0.5em", "thinspace": "0.1667em", "medspace": "0.2222em",
+	"thickspace": "0.2778em", "negthinspace": "-0.1667em",
+}
+
+// texAccents maps accent commands to the mark placed over (or, for the
+// under* commands, under) their argument and whether it stretches.
+var texAccents = map[string]struct {
+	mark    string
+	stretch bool
+	under   bool
+}{
+	"hat": {"^", false, false}, "widehat": {"^", true, false},
+	"bar": {"¯", false, false}, "overline": {"‾", true, false},
+	"vec": {"→", false, false}, "overrightarrow": {"→", true, false},
+	"overleftarrow": {"←", true, false}, "dot": {"˙", false, false},
+	"ddot": {"¨", false, false}, "tilde": {"~", false, false},
+	"widetilde": {"~", true, false}, "check": {"ˇ", false, false},
+	"breve": {"˘", false, false}, "acute": {"´", false, false},
+	"grave": {"`", false, false}, "mathring": {"˚", false, false},
+	"overbrace": {"⏞", true, false}, "underline": {"_", true, true},
+	"underbrace": {"⏟", true, true},
+}
+
+var texVariants = map[string]string{
+	"mathrm": "normal", "mathbf": "bold", "boldsymbol": "bold", "bm": "bold",
+	"mathit": "italic", "mathbb": "double-struck", "mathcal": "script",
+	"mathscr": "script", "mathfrak": "fraktur", "mathsf": "sans-serif",
+	"mathtt": "monospace",
+}
+
+var texTextCommands = map[string]string{
+	"text": "", "textrm": "", "mbox": "", "textnormal": "",
+	"textbf": "bold", "textit": "italic", "texttt": "monospace",
+	"textsf": "sans-serif",
+}
+
+// texDelimiterSizes are the heights of \big and friends.
+var texDelimiterSizes = map[string]string{
+	"big": "1.2em", "bigl": "1.2em", "bigr": "1.2em", "bigm": "1.2em",
+	"Big": "1.8em", "Bigl": "1.8em", "Bigr": "1.8em", "Bigm": "1.8em",
+	"bigg": "2.4em", "biggl": "2.4em", "biggr": "2.4em", "biggm": "2.4em",
+	"Bigg": "3em", "Biggl": "3em", "Biggr": "3em", "Biggm": "3em",
+}
+
+// texEnvironments maps supported environments to the delimiters around the
+// table and its column alignment.
+var texEnvironments = map[string]struct {
+	open, close string
+	align       string
+}{
+	"matrix": {"", "", ""}, "smallmatrix": {"", "", ""},
+	"pmatrix": {"(", ")", ""}, "bmatrix": {"[", "]", ""},
+	"Bmatrix": {"{", "}", ""}, "vmatrix": {"|", "|", ""},
+	"Vmatrix": {"‖", "‖", ""}, "cases": {"{", "", "left left"},
+	"aligned": {"", "", "right left"}, "align": {"", "", "right left"},
+	"align*": {"", "", "right left"}, "gathered": {"", "", ""},
+	"gather": {"", "", ""}, "gather*": {"", "", ""}, "array": {"", "", ""},
+	"split": {"", "", "right left"},
+}
+
+// texNegations are the precomposed forms of relations negated with \not.
+var texNegations = map[string]string{
+	"=": "≠", "<": "≮", ">": "≯", "≤": "≰", "≥": "≱", "∈": "∉", "∋": "∌",
+	"⊂": "⊄", "⊃": "⊅", "⊆": "⊈", "⊇": "⊉", "≡": "≢", "∼": "≁", "≈": "≉",
+	"≅": "≇", "∃": "∄", "∣": "∤", "∥": "∦",
+}
+
+// texIgnored are commands that only affect numbering or layout TeX does
+// outside the formula, and are dropped.
+var texIgnored = map[string]bool{
+	"nonumber": true, "notag": true, "limits": true, "nolimits": true,
+	"hline": true,
+}
+
+// texToMathML converts TeX math to a MathML <math> element. Commands it does
+// not know are rendered as <merror> and returned so callers can report them.
+func texToMathML(tex string, display bool) (string, []string) {
+	p := &texParser{src: []rune(tex), display: display}
+
+	var nodes []*mmlNode
+	for {
+		nodes = append(nodes, p.parseRow(false)...)
+		if p.pos >= len(p.src) {
+			break
+		}
+		// Skip a stray closing brace, \right or \end and carry on.
+		if p.src[p.pos] == '}' {
+			p.pos++
+		} else {
+			p.readCommand()
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		b.WriteString(` display="block"`)
+	}
+	b.WriteString("><semantics>")
+	newMML("mrow", nodes...).write(&b)
+	b.WriteString(`<annotation encoding="application/x-tex">`)
+	b.WriteString(mmlEscaper.Replace(tex))
+	b.WriteString("</annotation></semantics></math>")
+	return b.String(), p.unsupported
+}
+
+// texParser is a recursive descent parser for the subset of TeX math
+// supported by KaTeX that is commonly used in documents.
+type texParser struct {
+	src         []rune
+	pos         int
+	display     bool
+	unsupported []string
+}
+
+func (p *texParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// peekCommand returns the name of the command at the current position, or ""
+// when there is none.
+func (p *texParser) peekCommand() string {
+	pos := p.pos
+	name := p.readCommand()
+	p.pos = pos
+	return name
+}
+
+// readCommand consumes a \command and returns its name without the backslash.
+func (p *texParser) readCommand() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '\\' {
+		return ""
+	}
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.src) && isASCIILetter(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start && p.pos < len(p.src) {
+		p.pos++ // control symbol such as \{ or \,
+	}
+	name := string(p.src[start:p.pos])
+	if name == "begin" || name == "end" {
+		return name
+	}
+	// Starred forms such as \operatorname* behave like the plain command.
+	if p.pos < len(p.src) && p.src[p.pos] == '*' && isASCIILetter(p.src[start]) {
+		p.pos++
+	}
+	return name
+}
+
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// atRowEnd reports whether the parser is at the end of a row: the end of the
+// input, a closing brace, \right or \end, and in tables & or \\.
+func (p *texParser) atRowEnd(inTable bool) bool {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] == '}' {
+		return true
+	}
+	if inTable && p.src[p.pos] == '&' {
+		return true
+	}
+	switch p.peekCommand() {
+	case "right", "end":
+		return true
+	case "\\":
+		return inTable
+	}
+	return false
+}
+
+// parseRow parses atoms with their scripts up to the end of the row.
+func (p *texParser) parseRow(inTable bool) []*mmlNode {
+	var nodes []*mmlNode
+	for !p.atRowEnd(inTable) {
+		switch p.peekCommand() {
+		case "over", "choose":
+			// Infix fractions take everything before and after them.
+			name := p.readCommand()
+			frac := newMML("mfrac", mrow(nodes), mrow(p.parseRow(inTable)))
+			if name == "choose" {
+				frac.attr("linethickness", "0")
+				return []*mmlNode{newMML("mrow", texFence("("), frac, texFence(")"))}
+			}
+			return []*mmlNode{frac}
+		case "displaystyle", "textstyle":
+			style := p.readCommand() == "displaystyle"
+			rest := newMML("mstyle", p.parseRow(inTable)...).attr("displaystyle", boolString(style))
+			return append(nodes, rest)
+		case "color":
+			p.readCommand()
+			color := p.readRawGroup()
+			return append(nodes, newMML("mstyle", p.parseRow(inTable)...).attr("mathcolor", color))
+		case "\\":
+			p.readCommand() // line break outside a table
+			continue
+		}
+
+		if p.src[p.pos] == '&' {
+			p.pos++ // alignment point outside a table
+			continue
+		}
+
+		atom := p.parseAtom()
+		if atom == nil {
+			continue
+		}
+		node := p.parseScripts(atom)
+		nodes = append(nodes, node)
+		if atom.function && !p.atOpeningDelimiter() {
+			nodes = append(nodes, mspace("0.1667em"))
+		}
+	}
+	return nodes
+}
+
+func (p *texParser) atOpeningDelimiter() bool {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return true
+	}
+	switch p.src[p.pos] {
+	case '(', '[', '|':
+		return true
+	}
+	switch p.peekCommand() {
+	case "left", "{", "langle", "lvert", "lVert", "big", "Big", "bigg", "Bigg", "bigl", "Bigl", "biggl", "Biggl":
+		return true
+	}
+	return false
+}
+
+// parseScripts attaches any ^, _ and ' following base.
+func (p *texParser) parseScripts(base *mmlNode) *mmlNode {
+	var sub, sup *mmlNode
+	limits := base.limits && p.display
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			break
+		}
+		switch p.src[p.pos] {
+		case '^':
+			p.pos++
+			sup = p.parseArg()
+			continue
+		case '_':
+			p.pos++
+			sub = p.parseArg()
+			continue
+		case '\'':
+			primes := ""
+			for p.pos < len(p.src) && p.src[p.pos] == '\'' {
+				primes += "′"
+				p.pos++
+			}
+			sup = newMMLText("mo", primes)
+			continue
+		}
+		switch p.peekCommand() {
+		case "limits":
+			p.readCommand()
+			limits = true
+			continue
+		case "nolimits":
+			p.readCommand()
+			limits = false
+			continue
+		}
+		break
+	}
+
+	switch {
+	case sub != nil && sup != nil && limits:
+		return newMML("munderover", base, sub, sup)
+	case sub != nil && sup != nil:
+		return newMML("msubsup", base, sub, sup)
+	case sub != nil && limits:
+		return newMML("munder", base, sub)
+	case sub != nil:
+		return newMML("msub", base, sub)
+	case sup != nil && limits:
+		return newMML("mover", base, sup)
+	case sup != nil:
+		return newMML("msup", base, sup)
+	}
+	return base
+}
+
+// parseArg parses a command argument or script: a braced group, a command,
+// or a single character.
+func (p *texParser) parseArg() *mmlNode {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return newMML("mrow")
+	}
+	switch r := p.src[p.pos]; {
+	case r == '{':
+		return p.parseGroup()
+	case r == '\\':
+		if atom := p.parseAtom(); atom != nil {
+			return atom
+		}
+		return newMML("mrow")
+	default:
+		p.pos++
+		return texChar(r)
+	}
+}
+
+// parseGroup parses {...}.
+func (p *texParser) parseGroup() *mmlNode {
+	p.pos++ // {
+	nodes := p.parseRow(false)
+	if p.pos < len(p.src) && p.src[p.pos] == '}' {
+		p.pos++
+	}
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return newMML("mrow", nodes...)
+}
+
+// readRawGroup returns the unparsed contents of a braced argument, or a
+// single character when there are no braces.
+func (p *texParser) readRawGroup() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	if p.src[p.pos] != '{' {
+		p.pos++
+		return string(p.src[p.pos-1])
+	}
+	depth, start := 0, p.pos+1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1])
+			}
+		}
+	}
+	return string(p.src[start:])
+}
+
+// readOptional returns the contents of an optional [...] argument.
+func (p *texParser) readOptional() (string, bool) {
+	p.skipSpace()
+	if p.pos >= len(p.src) || p.src[p.pos] != '[' {
+		return "", false
+	}
+	depth, start := 0, p.pos+1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ']':
+			if depth == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1]), true
+			}
+		}
+	}
+	return string(p.src[start:]), true
+}
+
+// parseSub parses tex with a nested parser, collecting its unsupported
+// commands.
+func (p *texParser) parseSub(tex string) *mmlNode {
+	sub := &texParser{src: []rune(tex), display: p.display}
+	nodes := sub.parseRow(false)
+	p.unsupported = append(p.unsupported, sub.unsupported...)
+	return mrow(nodes)
+}
+
+// texChar converts a single character outside a command.
+func texChar(r rune) *mmlNode {
+	switch {
+	case r >= '0' && r <= '9':
+		return newMMLText("mn", string(r))
+	case unicode.IsLetter(r):
+		return newMMLText("mi", string(r))
+	case r == '-':
+		return newMMLText("mo", "−")
+	case r == '*':
+		return newMMLText("mo", "∗")
+	case r == '~':
+		return newMMLText("mtext", " ")
+	case r == '(' || r == ')' || r == '[' || r == ']' || r == '|':
+		return newMMLText("mo", string(r)).attr("stretchy", "false")
+	}
+	return newMMLText("mo", string(r))
+}
+
+// texFence is a stretchy delimiter for \left, \right and environments.
+func texFence(delim string) *mmlNode {
+	return newMMLText("mo", delim).attr("fence", "true").attr("stretchy", "true")
+}
+
+// readDelimiter reads the delimiter after \left, \right or \big. An empty
+// string stands for the invisible "." delimiter.
+func (p *texParser) readDelimiter() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	if p.src[p.pos] != '\\' {
+		r := p.src[p.pos]
+		p.pos++
+		if r == '.' {
+			return ""
+		}
+		return string(r)
+	}
+	switch name := p.readCommand(); name {
+	case "{", "}":
+		return name
+	case "|":
+		return "‖"
+	default:
+		if op, ok := texOperators[name]; ok {
+			return op
+		}
+		p.unsupported = append(p.unsupported, `\`+name)
+		return ""
+	}
+}
+
+// parseAtom parses a single item without scripts. It returns nil for input
+// that produces no output, such as \label.
+func (p *texParser) parseAtom() *mmlNode {
+	r := p.src[p.pos]
+	switch {
+	case r == '{':
+		return p.parseGroup()
+	case r >= '0' && r <= '9' || r == '.' && p.pos+1 < len(p.src) && p.src[p.pos+1] >= '0' && p.src[p.pos+1] <= '9':
+		start := p.pos
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' ||
+			p.src[p.pos] == '.' && p.pos+1 < len(p.src) && p.src[p.pos+1] >= '0' && p.src[p.pos+1] <= '9') {
+			p.pos++
+		}
+		return newMMLText("mn", string(p.src[start:p.pos]))
+	case r != '\\':
+		p.pos++
+		return texChar(r)
+	}
+
+	name := p.readCommand()
+	if s, ok := texIdentifiers[name]; ok {
+		return newMMLText("mi", s)
+	}
+	if s, ok := texUprightIdentifiers[name]; ok {
+		return newMMLText("mi", s).attr("mathvariant", "normal")
+	}
+	if s, ok := texOperators[name]; ok {
+		return newMMLText("mo", s)
+	}
+	if op, ok := texLargeOperators[name]; ok {
+		n := newMMLText("mo", op.symbol).attr("largeop", "true")
+		if op.limits {
+			n.attr("movablelimits", "true")
+		}
+		n.limits = op.limits
+		return n
+	}
+	if limits, ok := texFunctions[name]; ok {
+		text := name
+		if alt, ok := texFunctionNames[name]; ok {
+			text = alt
+		}
+		n := newMMLText("mi", text)
+		if len(text) == 1 {
+			n.attr("mathvariant", "normal")
+		}
+		n.limits, n.function = limits, true
+		return n
+	}
+	if width, ok := texSpaces[name]; ok {
+		return mspace(width)
+	}
+	if accent, ok := texAccents[name]; ok {
+		mark := newMMLText("mo", accent.mark).attr("stretchy", boolString(accent.stretch))
+		if accent.under {
+			n := newMML("munder", p.parseArg(), mark).attr("accentunder", "true")
+			n.limits = name == "underbrace"
+			return n
+		}
+		n := newMML("mover", p.parseArg(), mark).attr("accent", "true")
+		n.limits = name == "overbrace"
+		return n
+	}
+	if variant, ok := texVariants[name]; ok {
+		arg := p.parseArg()
+		applyVariant(arg, variant)
+		return arg
+	}
+	if variant, ok := texTextCommands[name]; ok {
+		n := newMMLText("mtext", p.readRawGroup())
+		if variant != "" {
+			n.attr("mathvariant", variant)
+		}
+		return n
+	}
+	if size, ok := texDelimiterSizes[name]; ok {
+		return newMMLText("mo", p.readDelimiter()).attr("minsize", size).attr("maxsize", size)
+	}
+	if texIgnored[name] {
+		return nil
+	}
+
+	switch name {
+	case "{", "}", "#", "%", "&", "$", "_":
+		return newMMLText("mo", name)
+	case "|":
+		return newMMLText("mo", "‖")
+	case "\\":
+		return nil
+	case "frac", "dfrac", "tfrac", "cfrac":
+		frac := newMML("mfrac", p.parseArg(), p.parseArg())
+		if name == "dfrac" || name == "cfrac" {
+			return newMML("mstyle", frac).attr("displaystyle", "true")
+		}
+		if name == "tfrac" {
+			return newMML("mstyle", frac).attr("displaystyle", "false")
+		}
+		return frac
+	case "binom", "dbinom", "tbinom":
+		frac := newMML("mfrac", p.parseArg(), p.parseArg()).attr("linethickness", "0")
+		return newMML("mrow", texFence("("), frac, texFence(")"))
+	case "sqrt":
+		if index, ok := p.readOptional(); ok {
+			return newMML("mroot", p.parseArg(), p.parseSub(index))
+		}
+		return newMML("msqrt", p.parseArg())
+	case "left":
+		open := p.readDelimiter()
+		inner := p.parseRow(false)
+		closing := ""
+		if p.peekCommand() == "right" {
+			p.readCommand()
+			closing = p.readDelimiter()
+		}
+		nodes := append([]*mmlNode{texFence(open)}, inner...)
+		return newMML("mrow", append(nodes, texFence(closing))...)
+	case "middle":
+		return texFence(p.readDelimiter())
+	case "operatorname":
+		n := newMMLText("mi", p.readRawGroup())
+		n.function = true
+		return n
+	case "overset", "stackrel":
+		over := p.parseArg()
+		return newMML("mover", p.parseArg(), over)
+	case "underset":
+		under := p.parseArg()
+		return newMML("munder", p.parseArg(), under)
+	case "textcolor":
+		color := p.readRawGroup()
+		return newMML("mstyle", p.parseArg()).attr("mathcolor", color)
+	case "phantom":
+		return newMML("mphantom", p.parseArg())
+	case "not":
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil
+		}
+		n := p.parseAtom()
+		if n != nil && n.tag == "mo" {
+			if negated, ok := texNegations[n.text]; ok {
+				n.text = negated
+			} else {
+				n.text += "\u0338" // combining long solidus overlay
+			}
+		}
+		return n
+	case "pmod":
+		return newMML("mrow", mspace("1em"), texChar('('), newMMLText("mi", "mod"),
+			mspace("0.3333em"), p.parseArg(), texChar(')'))
+	case "label", "tag":
+		p.readRawGroup()
+		return nil
+	case "begin":
+		return p.parseEnvironment()
+	}
+
+	p.unsupported = append(p.unsupported, `\`+name)
+	return newMML("merror", newMMLText("mtext", `\`+name))
+}
+
+// parseEnvironment parses \begin{name}...\end{name} into a table.
+func (p *texParser) parseEnvironment() *mmlNode {
+	name := p.readRawGroup()
+	env, ok := texEnvironments[name]
+	if !ok {
+		p.unsupported = append(p.unsupported, `\begin{`+name+`}`)
+	}
+	if name == "array" {
+		p.readRawGroup() // column specification
+	}
+
+	var rows []*mmlNode
+	for {
+		var cells []*mmlNode
+		for {
+			cells = append(cells, newMML("mtd", p.parseRow(true)...))
+			if p.pos < len(p.src) && p.src[p.pos] == '&' {
+				p.pos++
+				continue
+			}
+			break
+		}
+
+		cmd := p.peekCommand()
+		if cmd == "\\" || cmd == "end" {
+			p.readCommand()
+		}
+		if cmd == "end" {
+			p.readRawGroup()
+		}
+		if cmd != "\\" && len(cells) == 1 && len(cells[0].children) == 0 {
+			break // empty row after a trailing \\
+		}
+		rows = append(rows, newMML("mtr", cells...))
+		if cmd != "\\" {
+			break
+		}
+	}
+
+	table := newMML("mtable", rows...)
+	if env.align != "" {
+		table.attr("columnalign", env.align)
+	}
+	if strings.HasPrefix(name, "align") || name == "aligned" || name == "split" || name == "cases" {
+		table.attr("displaystyle", "true")
+	}
+	if env.open == "" && env.close == "" {
+		return table
+	}
+
+	nodes := []*mmlNode{texFence(env.open), table}
+	if env.close != "" {
+		nodes = append(nodes, texFence(env.close))
+	}
+	return newMML("mrow", nodes...)
+}
+
+// applyVariant sets a font variant such as bold or double-struck on the
+// identifiers and numbers in n. Letters are mapped to the Unicode
+// mathematical alphanumeric symbols, which render in every browser.
+func applyVariant(n *mmlNode, variant string) {
+	if n.tag == "mi" || n.tag == "mn" {
+		if variant == "normal" {
+			if n.tag == "mi" {
+				n.attr("mathvariant", "normal")
+			}
+			return
+		}
+		var b strings.Builder
+		for _, r := range n.text {
+			b.WriteRune(mathAlphanumeric(variant, r))
+		}
+		n.text = b.String()
+		return
+	}
+	for _, c := range n.children {
+		applyVariant(c, variant)
+	}
+}
+
+// mathAlphanumericBases are the code points of A, a and 0 in each variant
+// of the Mathematical Alphanumeric Symbols block.
+var mathAlphanumericBases = map[string][3]rune{
+	"bold":          {0x1D400, 0x1D41A, 0x1D7CE},
+	"italic":        {0x1D434, 0x1D44E, 0},
+	"script":        {0x1D49C, 0x1D4B6, 0},
+	"fraktur":       {0x1D504, 0x1D51E, 0},
+	"double-struck": {0x1D538, 0x1D552, 0x1D7D8},
+	"sans-serif":    {0x1D5A0, 0x1D5BA, 0x1D7E2},
+	"monospace":     {0x1D670, 0x1D68A, 0x1D7F6},
+}
+
+// mathAlphanumericHoles are letters encoded in the Letterlike Symbols block
+// instead of their place in Mathematical Alphanumeric Symbols.
+var mathAlphanumericHoles = map[string]map[rune]rune{
+	"italic": {'h': 'ℎ'},
+	"script": {'B': 'ℬ', 'E': 'ℰ', 'F': 'ℱ', 'H': 'ℋ', 'I': 'ℐ', 'L': 'ℒ',
+		'M': 'ℳ', 'R': 'ℛ', 'e': 'ℯ', 'g': 'ℊ', 'o': 'ℴ'},
+	"fraktur":       {'C': 'ℭ', 'H': 'ℌ', 'I': 'ℑ', 'R': 'ℜ', 'Z': 'ℨ'},
+	"double-struck": {'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ'},
+}
+
+func mathAlphanumeric(variant string, r rune) rune {
+	if hole, ok := mathAlphanumericHoles[variant][r]; ok {
+		return hole
+	}
+	bases, ok := mathAlphanumericBases[variant]
+	if !ok {
+		return r
+	}
+	switch {
+	case r >= 'A' && r <= 'Z':
+		return bases[0] + r - 'A'
+	case r >= 'a' && r <= 'z':
+		return bases[1] + r - 'a'
+	case r >= '0' && r <= '9' && bases[2] != 0:
+		return bases[2] + r - '0'
+	}
+	return r
+}
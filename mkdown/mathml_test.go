@@ -0,0 +1,94 @@
+package mkdown
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMathMLOutput(t *testing.T) {
+	var warnings []string
+	c := New(
+		WithMathOutput(MathMathML),
+		WithWarningHandler(func(path, message string) {
+			warnings = append(warnings, path+": "+message)
+		}),
+	)
+
+	source := "---\ntitle: Math\n---\nInline $x^2$ here.\n\n$$\n\\frac{a}{b} \\unknowncmd\n$$\n\nA $\\foo$ b.\n"
+	doc, err := c.RenderDocument(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+
+	content := string(doc.Content)
+	for _, want := range []string{
+		`<span class="math math-inline"><math xmlns="http://www.w3.org/1998/Math/MathML">`,
+		`<msup><mi>x</mi><mn>2</mn></msup>`,
+		`<div class="math math-display"><math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`,
+		`<mfrac><mi>a</mi><mi>b</mi></mfrac>`,
+		`<merror><mtext>\unknowncmd</mtext></merror>`,
+		`<merror><mtext>\foo</mtext></merror>`,
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in:\n%s", want, content)
+		}
+	}
+	if len(doc.RequiredScripts) != 0 || strings.Contains(string(doc.Scripts), "katex") {
+		t.Errorf("MathML output should not load KaTeX, got %v", doc.RequiredScripts)
+	}
+	if len(doc.Warnings) != 1 || !strings.Contains(doc.Warnings[0], `\unknowncmd (line 7), \foo (line 10)`) {
+		t.Errorf("expected a warning naming the command and line, got %v", doc.Warnings)
+	}
+
+	// Whole-page conversions pass warnings to the handler with the file.
+	inputPath := filepath.Join(t.TempDir(), "math.md")
+	if err := os.WriteFile(inputPath, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenderFile(inputPath); err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	want := inputPath + `: unsupported LaTeX in math, shown as errors: \unknowncmd (line 7), \foo (line 10)`
+	if len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("expected the handler to receive %q, got %v", want, warnings)
+	}
+}
+
+func TestTexToMathML(t *testing.T) {
+	tests := []struct {
+		tex         string
+		display     bool
+		contains    string
+		unsupported []string
+	}{
+		{`\alpha + \beta`, false, `<mi>α</mi><mo>+</mo><mi>β</mi>`, nil},
+		{`x_i^2`, false, `<msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup>`, nil},
+		{`3.14r`, false, `<mn>3.14</mn><mi>r</mi>`, nil},
+		{`\sqrt[3]{x}`, false, `<mroot><mi>x</mi><mn>3</mn></mroot>`, nil},
+		{`\sum_{k=0}^n k`, true, `<munderover><mo largeop="true" movablelimits="true">∑</mo>`, nil},
+		{`\sum_{k=0}^n k`, false, `<msubsup><mo largeop="true" movablelimits="true">∑</mo>`, nil},
+		{`\mathbb{N} \mathbf{v}`, false, `<mi>ℕ</mi><mi>𝐯</mi>`, nil},
+		{`\text{if } x`, false, `<mtext>if </mtext><mi>x</mi>`, nil},
+		{`\left[ a \right.`, false, `<mo fence="true" stretchy="true">[</mo><mi>a</mi><mo fence="true" stretchy="true"></mo>`, nil},
+		{`\begin{cases} 1 & x > 0 \\ 0 & \text{otherwise} \end{cases}`, true, `<mtable columnalign="left left" displaystyle="true"><mtr><mtd><mn>1</mn></mtd>`, nil},
+		{`\hat{x} \not= y`, false, `<mover accent="true"><mi>x</mi><mo stretchy="false">^</mo></mover><mo>≠</mo>`, nil},
+		{`a < b`, false, `<mi>a</mi><mo>&lt;</mo><mi>b</mi>`, nil},
+		{`\foo{x} + \begin{weird} y \end{weird}`, false, `<merror><mtext>\foo</mtext></merror>`, []string{`\foo`, `\begin{weird}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tex, func(t *testing.T) {
+			got, unsupported := texToMathML(tt.tex, tt.display)
+			if !strings.Contains(got, tt.contains) {
+				t.Errorf("expected %q in:\n%s", tt.contains, got)
+			}
+			if fmt.Sprint(unsupported) != fmt.Sprint(tt.unsupported) {
+				t.Errorf("expected unsupported %v, got %v", tt.unsupported, unsupported)
+			}
+		})
+	}
+}
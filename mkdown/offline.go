@@ -0,0 +1,98 @@
+package mkdown
+
+import (
+	"embed"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+//go:embed assets
+var embeddedAssets embed.FS
+
+// offlineAssets holds the runtimes inlined into offline pages, laid out as
+// described in assets/README.md. Tests replace it with an in-memory copy.
+var offlineAssets fs.FS = mustSub(embeddedAssets, "assets")
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// readAsset returns an offline asset, explaining how to bundle it when this
+// build of mkdown was made without it.
+func readAsset(name string) ([]byte, error) {
+	data, err := fs.ReadFile(offlineAssets, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("offline mode needs %s, which is not bundled in this build of mkdown (run 'make assets' and rebuild)", name)
+	}
+	return data, err
+}
+
+// dataURI encodes data as a base64 data: URL.
+func dataURI(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// inlineScript wraps JavaScript source in a <script> element, escaping any
+// "</script" inside it so the source cannot end the element early.
+func inlineScript(source []byte) string {
+	js := strings.ReplaceAll(string(source), "</script", `<\/script`)
+	return "<script>" + js + "</script>\n"
+}
+
+// offlineMermaidScript returns script, the contents of scripts/mermaid.js,
+// with the bundled Mermaid runtime inlined in place of the CDN import.
+func offlineMermaidScript(script string) (string, error) {
+	runtime, err := readAsset("mermaid/mermaid.min.js")
+	if err != nil {
+		return "", err
+	}
+	script = strings.Replace(script, mermaidImport, "const mermaid = window.mermaid;", 1)
+	return inlineScript(runtime) + script, nil
+}
+
+// fontFaceSrc matches the src descriptor of an @font-face rule.
+var fontFaceSrc = regexp.MustCompile(`src:[^;}]*`)
+
+// fontURL matches a relative font reference in the KaTeX stylesheet.
+var fontURL = regexp.MustCompile(`url\((fonts/[^)]+\.woff2)\)`)
+
+// offlineKatexScript returns the KaTeX stylesheet, with its WOFF2 fonts as
+// data URIs, and runtime followed by scripts/katex.js.
+func offlineKatexScript() (string, error) {
+	runtime, err := readAsset("katex/katex.min.js")
+	if err != nil {
+		return "", err
+	}
+	css, err := readAsset("katex/katex.min.css")
+	if err != nil {
+		return "", err
+	}
+
+	var fontErr error
+	inlined := fontFaceSrc.ReplaceAllStringFunc(string(css), func(src string) string {
+		match := fontURL.FindStringSubmatch(src)
+		if match == nil {
+			return src
+		}
+		font, err := readAsset(path.Join("katex", match[1]))
+		if err != nil {
+			fontErr = err
+			return src
+		}
+		return `src:url(` + dataURI("font/woff2", font) + `) format("woff2")`
+	})
+	if fontErr != nil {
+		return "", fontErr
+	}
+
+	return "<style>" + inlined + "</style>\n" + inlineScript(runtime) + katexScript, nil
+}
@@ -0,0 +1,119 @@
+package mkdown
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestOfflineScripts(t *testing.T) {
+	saved := offlineAssets
+	defer func() { offlineAssets = saved }()
+
+	offlineAssets = fstest.MapFS{
+		"katex/katex.min.js":                   {Data: []byte("window.katex={};")},
+		"katex/katex.min.css":                  {Data: []byte(`@font-face{font-family:KaTeX_Main;src:url(fonts/KaTeX_Main-Regular.woff2) format("woff2"),url(fonts/KaTeX_Main-Regular.woff) format("woff")}.katex{color:red}`)},
+		"katex/fonts/KaTeX_Main-Regular.woff2": {Data: []byte("font")},
+		"mermaid/mermaid.min.js":               {Data: []byte(`window.mermaid={};var s="</script>";`)},
+	}
+
+	c := New(WithOffline(true), WithMermaid(true), WithMath(true))
+	doc, err := c.RenderDocument(context.Background(), []byte("$x$\n\n```mermaid\ngraph TD; A-->B\n```\n"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+
+	scripts := string(doc.Scripts)
+	for _, want := range []string{
+		"<script>window.katex={};</script>",
+		`src:url(data:font/woff2;base64,Zm9udA==) format("woff2")}`,
+		".katex{color:red}",
+		`<script>window.mermaid={};var s="<\/script>";</script>`,
+		"const mermaid = window.mermaid;",
+		"theme: 'dark'",
+	} {
+		if !strings.Contains(scripts, want) {
+			t.Errorf("expected %q in offline scripts:\n%s", want, scripts)
+		}
+	}
+	if strings.Contains(scripts, "cdn.jsdelivr.net") {
+		t.Error("offline scripts should not reference the CDN")
+	}
+
+	// Documents only get the runtimes they use.
+	doc, err = c.RenderDocument(context.Background(), []byte("# No math"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if doc.Scripts != "" {
+		t.Errorf("expected no scripts, got %s", doc.Scripts)
+	}
+}
+
+func TestOfflineMissingAsset(t *testing.T) {
+	saved := offlineAssets
+	defer func() { offlineAssets = saved }()
+	offlineAssets = fstest.MapFS{}
+
+	c := New(WithOffline(true), WithMath(true))
+	_, err := c.RenderDocument(context.Background(), []byte("$x$"))
+	if err == nil || !strings.Contains(err.Error(), "katex/katex.min.js") {
+		t.Errorf("expected an error naming the missing asset, got %v", err)
+	}
+}
+
+// TestOfflineBundledAssets checks the runtimes embedded into the binary
+// against assets/SHA256SUMS and renders a page with them.
+func TestOfflineBundledAssets(t *testing.T) {
+	sums, err := fs.ReadFile(embeddedAssets, "assets/SHA256SUMS")
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Skip("offline assets are not bundled in this tree: run 'make assets-sums' and commit mkdown/assets")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(sums)), "\n") {
+		sum, name, ok := strings.Cut(line, "  ")
+		if !ok {
+			t.Fatalf("malformed SHA256SUMS line %q", line)
+		}
+		listed[name] = true
+		data, err := fs.ReadFile(offlineAssets, name)
+		if err != nil {
+			t.Errorf("%s is listed in SHA256SUMS but not embedded: %v", name, err)
+			continue
+		}
+		if got := fmt.Sprintf("%x", sha256.Sum256(data)); got != sum {
+			t.Errorf("%s has sha256 %s, want %s", name, got, sum)
+		}
+	}
+	css, err := fs.ReadFile(offlineAssets, "katex/katex.min.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	required := []string{"katex/katex.min.js", "katex/katex.min.css", "mermaid/mermaid.min.js"}
+	for _, match := range fontURL.FindAllStringSubmatch(string(css), -1) {
+		required = append(required, "katex/"+match[1])
+	}
+	for _, name := range required {
+		if !listed[name] {
+			t.Errorf("%s is not bundled", name)
+		}
+	}
+
+	doc, err := New(WithOffline(true), WithMermaid(true), WithMath(true)).RenderDocument(context.Background(), []byte("$x$\n\n```mermaid\ngraph TD; A-->B\n```\n"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	scripts := string(doc.Scripts)
+	if strings.Contains(scripts, "cdn.jsdelivr.net") || !strings.Contains(scripts, "data:font/woff2;base64,") {
+		t.Error("expected the bundled runtimes and fonts to be inlined")
+	}
+}
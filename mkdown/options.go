@@ -0,0 +1,179 @@
+package mkdown
+
+// Option configures a Converter created with New.
+type Option func(*ConverterOptions)
+
+// New returns a Converter configured by opts. Without options it renders
+// with the dark theme, no Mermaid or math support and links to markdown
+// files pointed at the generated pages, like the CLI.
+func New(opts ...Option) *Converter {
+	options := ConverterOptions{Theme: "dark", RewriteLinks: true}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return NewConverterWithOptions(options)
+}
+
+// WithTheme selects the built-in theme: "dark", "light" or ThemeAuto.
+func WithTheme(name string) Option {
+	return func(o *ConverterOptions) {
+		o.Theme = name
+	}
+}
+
+// WithThemeCSS replaces the built-in theme stylesheet with the file at path.
+func WithThemeCSS(path string) Option {
+	return func(o *ConverterOptions) {
+		o.ThemeCSSPath = path
+	}
+}
+
+// WithMermaid enables rendering of ```mermaid code blocks as diagrams.
+func WithMermaid(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.EnableMermaid = enabled
+	}
+}
+
+// WithMath enables rendering of math with KaTeX.
+func WithMath(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.EnableMath = enabled
+	}
+}
+
+// WithMathOutput enables math and selects how it is rendered: MathKaTeX or
+// MathMathML.
+func WithMathOutput(format string) Option {
+	return func(o *ConverterOptions) {
+		o.EnableMath = true
+		o.MathOutput = format
+	}
+}
+
+// WithCopyButtons adds copy buttons and language badges to highlighted
+// code blocks.
+func WithCopyButtons(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.CopyButtons = enabled
+	}
+}
+
+// WithOffline inlines the bundled Mermaid and KaTeX runtimes so pages work
+// without network access.
+func WithOffline(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.Offline = enabled
+	}
+}
+
+// WithInlineImages embeds local images as data URIs.
+func WithInlineImages(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.InlineImages = enabled
+	}
+}
+
+// WithInlineCSS replaces links to local stylesheets with their contents.
+func WithInlineCSS(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.InlineCSS = enabled
+	}
+}
+
+// WithMaxInlineSize sets the largest file, in bytes, that is inlined.
+func WithMaxInlineSize(bytes int64) Option {
+	return func(o *ConverterOptions) {
+		o.MaxInlineSize = bytes
+	}
+}
+
+// WithTOC adds a table of contents sidebar to every page.
+func WithTOC(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.TOC = enabled
+	}
+}
+
+// WithTOCDepth sets the heading levels included in the table of contents.
+func WithTOCDepth(min, max int) Option {
+	return func(o *ConverterOptions) {
+		o.TOCMinDepth = min
+		o.TOCMaxDepth = max
+	}
+}
+
+// WithIncludes enables !include and {{< include >}} directives and code
+// blocks showing other files.
+func WithIncludes(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.Includes = enabled
+	}
+}
+
+// WithIncludeRoot sets the directory included files must be inside.
+func WithIncludeRoot(dir string) Option {
+	return func(o *ConverterOptions) {
+		o.IncludeRoot = dir
+	}
+}
+
+// WithWikiLinks enables [[wikilinks]] to other pages.
+func WithWikiLinks(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.WikiLinks = enabled
+	}
+}
+
+// WithWikiLinkRoot sets the directory searched for the pages wikilinks
+// name, with its subdirectories.
+func WithWikiLinkRoot(dir string) Option {
+	return func(o *ConverterOptions) {
+		o.WikiLinkRoot = dir
+	}
+}
+
+// WithRewriteLinks sets whether links to local markdown files are pointed
+// at the HTML pages generated from them, which New does by default.
+func WithRewriteLinks(enabled bool) Option {
+	return func(o *ConverterOptions) {
+		o.RewriteLinks = enabled
+	}
+}
+
+// WithOutputPath sets the function naming the HTML file generated from
+// each markdown file, which links between pages are made relative to.
+func WithOutputPath(fn func(path string) string) Option {
+	return func(o *ConverterOptions) {
+		o.OutputPath = fn
+	}
+}
+
+// WithWarningHandler sets the function called with conversion warnings.
+func WithWarningHandler(fn func(path, message string)) Option {
+	return func(o *ConverterOptions) {
+		o.WarningHandler = fn
+	}
+}
+
+// WithHighlightStyle sets the Chroma style used for syntax highlighting.
+func WithHighlightStyle(name string) Option {
+	return func(o *ConverterOptions) {
+		o.HighlightStyle = name
+	}
+}
+
+// WithCSS appends the stylesheet at path after the theme CSS.
+func WithCSS(path string) Option {
+	return func(o *ConverterOptions) {
+		o.CSSPath = path
+	}
+}
+
+// WithTemplate renders pages with the html/template file at path instead of
+// the built-in template.
+func WithTemplate(path string) Option {
+	return func(o *ConverterOptions) {
+		o.TemplatePath = path
+	}
+}
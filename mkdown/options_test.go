@@ -0,0 +1,35 @@
+package mkdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	c := New()
+	if c.theme != "dark" || c.enableMermaid || c.enableMath {
+		t.Errorf("unexpected defaults: theme=%s mermaid=%v math=%v", c.theme, c.enableMermaid, c.enableMath)
+	}
+
+	c = New(
+		WithTheme("light"),
+		WithMermaid(true),
+		WithMath(true),
+		WithHighlightStyle("dracula"),
+		WithCSS("extra.css"),
+		WithTemplate("page.html"),
+	)
+	if c.theme != "light" || !c.enableMermaid || !c.enableMath {
+		t.Errorf("options not applied: theme=%s mermaid=%v math=%v", c.theme, c.enableMermaid, c.enableMath)
+	}
+	if c.cssPath != "extra.css" || c.templatePath != "page.html" {
+		t.Errorf("path options not applied: css=%s template=%s", c.cssPath, c.templatePath)
+	}
+	want, err := HighlightCSS("dracula")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.themeStyles, want) {
+		t.Error("expected the dracula highlight CSS in the styles")
+	}
+}
@@ -0,0 +1,39 @@
+package mkdown
+
+import _ "embed"
+
+//go:embed scripts/mermaid.js
+var mermaidScript string
+
+//go:embed scripts/katex.js
+var katexScript string
+
+//go:embed scripts/toc.js
+var tocScript string
+
+//go:embed scripts/theme.js
+var themeScript string
+
+//go:embed scripts/copy.js
+var copyScript string
+
+// katexCDN loads the KaTeX stylesheet and runtime from jsDelivr. Offline
+// pages inline the same files from assets/katex instead.
+const katexCDN = `<link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/katex@0.16.9/dist/katex.min.css" integrity="sha384-n8MVd4RsNIU0tAv4ct0nTaAbDJwPJzDEaqSD1odI+WdtXRGWt2kTvGFasHpSy3SV" crossorigin="anonymous">
+<script defer src="https://cdn.jsdelivr.net/npm/katex@0.16.9/dist/katex.min.js" integrity="sha384-XjKyOOlGwcjNTAIQHIpgOno0Hl1YQqzUOEleOLALmuqehneUG+vnGctmUb0ZY0l8" crossorigin="anonymous"></script>
+`
+
+// mermaidImport is the line of scripts/mermaid.js that loads Mermaid from
+// jsDelivr. Offline pages replace it with the global defined by the inlined
+// runtime.
+const mermaidImport = "import mermaid from 'https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs';"
+
+// GetMermaidScript returns the Mermaid initialization script
+func GetMermaidScript() string {
+	return mermaidScript
+}
+
+// GetKatexScript returns the KaTeX initialization script
+func GetKatexScript() string {
+	return katexCDN + katexScript
+}
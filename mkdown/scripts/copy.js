@@ -0,0 +1,94 @@
+<script>
+  // Add a language badge and a copy button to each highlighted code block.
+  (() => {
+    const shells = ['bash', 'sh', 'shell', 'zsh', 'fish', 'console', 'shell-session', 'powershell', 'ps1', 'bat', 'cmd'];
+    const prompt = /^(?:\$|%|PS [^>]*>) /;
+
+    // Shell sessions are copied as the commands alone: prompts are removed
+    // and, when some lines have one, output lines are left out. Lines
+    // continuing a command with a trailing backslash are kept.
+    const commands = (text) => {
+      const lines = text.replace(/\n$/, '').split('\n');
+      if (!lines.some((line) => prompt.test(line))) {
+        return text;
+      }
+      const kept = [];
+      let continued = false;
+      for (const line of lines) {
+        if (prompt.test(line)) {
+          kept.push(line.replace(prompt, ''));
+        } else if (continued) {
+          kept.push(line);
+        } else {
+          continue;
+        }
+        continued = line.endsWith('\\');
+      }
+      return kept.join('\n') + '\n';
+    };
+
+    // The code of a block without its line numbers, which the table layout
+    // puts in a separate <pre>.
+    const code = (block) => {
+      const elements = block.querySelectorAll('pre code');
+      const clone = elements[elements.length - 1].cloneNode(true);
+      clone.querySelectorAll('.ln, .lnt').forEach((number) => number.remove());
+      return clone.textContent;
+    };
+
+    // navigator.clipboard needs a secure context, which pages opened from
+    // the file system or a plain http server may not be.
+    const copy = (text) => {
+      if (navigator.clipboard && window.isSecureContext) {
+        return navigator.clipboard.writeText(text);
+      }
+      const area = document.createElement('textarea');
+      area.value = text;
+      area.style.position = 'fixed';
+      area.style.opacity = '0';
+      document.body.appendChild(area);
+      area.select();
+      const ok = document.execCommand('copy');
+      area.remove();
+      return ok ? Promise.resolve() : Promise.reject(new Error('copy failed'));
+    };
+
+    document.addEventListener('DOMContentLoaded', () => {
+      document.querySelectorAll('.code-block[data-lang]').forEach((block) => {
+        const lang = block.dataset.lang;
+        const toolbar = document.createElement('div');
+        toolbar.className = 'code-toolbar';
+
+        const badge = document.createElement('span');
+        badge.className = 'code-lang';
+        badge.textContent = lang;
+
+        const button = document.createElement('button');
+        button.type = 'button';
+        button.className = 'code-copy';
+        button.textContent = 'Copy';
+        button.title = 'Copy to clipboard';
+        button.addEventListener('click', () => {
+          let text = code(block);
+          if (shells.includes(lang.toLowerCase())) {
+            text = commands(text);
+          }
+          copy(text).then(
+            () => { button.textContent = 'Copied'; },
+            () => { button.textContent = 'Failed'; },
+          ).finally(() => {
+            setTimeout(() => { button.textContent = 'Copy'; }, 1500);
+          });
+        });
+
+        toolbar.append(badge, button);
+        const title = block.querySelector('.code-title');
+        if (title) {
+          title.appendChild(toolbar);
+        } else {
+          block.insertBefore(toolbar, block.firstChild);
+        }
+      });
+    });
+  })();
+</script>
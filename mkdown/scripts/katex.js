@@ -0,0 +1,12 @@
+<script>
+  // Math is parsed at build time into .math elements holding the TeX source,
+  // so only those elements are typeset and nothing else on the page.
+  document.addEventListener('DOMContentLoaded', () => {
+    document.querySelectorAll('.math').forEach((el) => {
+      katex.render(el.textContent, el, {
+        displayMode: el.classList.contains('math-display'),
+        throwOnError: false
+      });
+    });
+  });
+</script>
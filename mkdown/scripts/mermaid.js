@@ -1,11 +1,16 @@
 <script type="module">
   import mermaid from 'https://cdn.jsdelivr.net/npm/mermaid@10/dist/mermaid.esm.min.mjs';
   
-  mermaid.initialize({ 
+  const config = {
     startOnLoad: false,
     theme: '{{THEME}}',
     securityLevel: 'loose'
-  });
+  };
+
+  // The auto theme draws diagrams to match the page's colour scheme
+  const auto = config.theme === 'auto';
+  const currentTheme = () => (window.mkdownColorScheme() === 'dark' ? 'dark' : 'default');
+  mermaid.initialize(auto ? { ...config, theme: currentTheme() } : config);
 
   // Convert code blocks with language-mermaid to mermaid divs
   document.addEventListener('DOMContentLoaded', () => {
@@ -21,6 +26,7 @@
       const div = document.createElement('div');
       div.className = 'mermaid';
       div.textContent = code;
+      div.dataset.source = code;
       
       // Create fullscreen button
       const fsBtn = document.createElement('button');
@@ -44,6 +50,18 @@
     
     // Render all mermaid diagrams
     mermaid.run();
+
+    // Redraw them when the reader switches between light and dark
+    if (auto) {
+      document.addEventListener('mkdown:themechange', () => {
+        mermaid.initialize({ ...config, theme: currentTheme() });
+        document.querySelectorAll('.mermaid').forEach((div) => {
+          div.removeAttribute('data-processed');
+          div.textContent = div.dataset.source;
+        });
+        mermaid.run();
+      });
+    }
     
     // Close fullscreen with Escape
     document.addEventListener('keydown', (e) => {
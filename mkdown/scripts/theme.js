@@ -0,0 +1,59 @@
+<script>
+  // Follow the system colour scheme unless the reader picked one with the
+  // toggle, which is remembered in localStorage. Runs before the body is
+  // drawn so a stored choice applies without a flash.
+  (() => {
+    const key = 'mkdown-theme';
+    const root = document.documentElement;
+    const media = window.matchMedia('(prefers-color-scheme: dark)');
+
+    const stored = () => {
+      try {
+        return localStorage.getItem(key);
+      } catch (e) {
+        return null; // storage disabled
+      }
+    };
+    const scheme = () => root.dataset.theme || (media.matches ? 'dark' : 'light');
+    const changed = () => document.dispatchEvent(new CustomEvent('mkdown:themechange', { detail: scheme() }));
+
+    // Other scripts, such as the Mermaid loader, read the current scheme here.
+    window.mkdownColorScheme = scheme;
+
+    const saved = stored();
+    if (saved === 'light' || saved === 'dark') {
+      root.dataset.theme = saved;
+    }
+
+    media.addEventListener('change', () => {
+      if (!root.dataset.theme) {
+        changed();
+      }
+    });
+
+    document.addEventListener('DOMContentLoaded', () => {
+      const button = document.createElement('button');
+      button.className = 'theme-toggle';
+      const label = () => {
+        const next = scheme() === 'dark' ? 'light' : 'dark';
+        button.textContent = next === 'dark' ? '☾' : '☀';
+        button.title = 'Switch to ' + next + ' theme';
+        button.setAttribute('aria-label', button.title);
+      };
+
+      button.addEventListener('click', () => {
+        root.dataset.theme = scheme() === 'dark' ? 'light' : 'dark';
+        try {
+          localStorage.setItem(key, root.dataset.theme);
+        } catch (e) {
+          // The choice lasts until the page is left.
+        }
+        changed();
+      });
+      document.addEventListener('mkdown:themechange', label);
+
+      label();
+      document.body.appendChild(button);
+    });
+  })();
+</script>
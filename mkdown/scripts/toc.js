@@ -0,0 +1,26 @@
+<script>
+  // Highlight the table of contents entry of the section being read.
+  document.addEventListener('DOMContentLoaded', () => {
+    const links = Array.from(document.querySelectorAll('nav.toc a[href^="#"]'));
+    const entries = links
+      .map((link) => ({ link, heading: document.getElementById(decodeURIComponent(link.hash.slice(1))) }))
+      .filter((entry) => entry.heading);
+    if (entries.length === 0) {
+      return;
+    }
+
+    const update = () => {
+      let current = entries[0];
+      for (const entry of entries) {
+        if (entry.heading.getBoundingClientRect().top > 80) {
+          break;
+        }
+        current = entry;
+      }
+      entries.forEach((entry) => entry.link.classList.toggle('active', entry.heading === current.heading));
+    };
+
+    document.addEventListener('scroll', update, { passive: true });
+    update();
+  });
+</script>
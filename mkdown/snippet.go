@@ -0,0 +1,215 @@
+package mkdown
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// snippetAttribute matches the attributes of a fenced code block that
+// include a snippet of another file, written after the language with or
+// without braces: ```go file=main.go lines=15-40 or
+// ```go {file="main.go" region="setup"}.
+var snippetAttribute = regexp.MustCompile(`(?:^|[\s{,])(file|lines|region)=("[^"]*"|[^\s,}]+)`)
+
+// snippet is the part of a file a code block includes.
+type snippet struct {
+	file   string
+	lines  string // "15-40", "15-" or "15", or "" for the whole file
+	region string // name of a [START name] ... [END name] region
+}
+
+// codeSnippet returns the snippet a fenced code block includes, if any.
+func codeSnippet(block *ast.FencedCodeBlock, source []byte) (snippet, bool) {
+	var s snippet
+	if block.Info == nil {
+		return s, false
+	}
+	info := block.Info.Segment.Value(source)
+	for _, match := range snippetAttribute.FindAllSubmatch(info, -1) {
+		value := strings.Trim(string(match[2]), `"`)
+		switch string(match[1]) {
+		case "file":
+			s.file = value
+		case "lines":
+			s.lines = value
+		case "region":
+			s.region = value
+		}
+	}
+	return s, s.file != "" || s.lines != "" || s.region != ""
+}
+
+// includeSnippets replaces the content of fenced code blocks with a file=
+// attribute by the lines of the file they name, resolved against dir and
+// required to be inside includeRoot. The included text is appended to
+// source, which is returned, and the blocks point into it. A missing file
+// or region is an error starting with the location of the code block,
+// which locate returns for an offset in source.
+func includeSnippets(root ast.Node, source []byte, includeRoot, dir string, locate func(offset int) string) ([]byte, error) {
+	// Copy so that appending never writes into the caller's buffer.
+	extended := source[:len(source):len(source)]
+	err := ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		block, ok := n.(*ast.FencedCodeBlock)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		s, ok := codeSnippet(block, source)
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+
+		content, err := s.read(includeRoot, dir)
+		if err != nil {
+			return ast.WalkStop, fmt.Errorf("%s: %w", locate(block.Info.Segment.Start), err)
+		}
+
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			content = append(content, '\n')
+		}
+		lines := text.NewSegments()
+		for len(content) > 0 {
+			end := bytes.IndexByte(content, '\n') + 1
+			lines.Append(text.NewSegment(len(extended), len(extended)+end))
+			extended = append(extended, content[:end]...)
+			content = content[end:]
+		}
+		block.SetLines(lines)
+		return ast.WalkSkipChildren, nil
+	})
+	return extended, err
+}
+
+// read returns the included lines of the file, which is relative to dir
+// and must be inside includeRoot, without the indentation they share.
+func (s snippet) read(includeRoot, dir string) ([]byte, error) {
+	if s.file == "" {
+		return nil, fmt.Errorf("code block has lines or region but no file to include")
+	}
+	if s.lines != "" && s.region != "" {
+		return nil, fmt.Errorf("cannot include %s: use either lines or region, not both", s.file)
+	}
+
+	path, err := confinedPath(includeRoot, dir, s.file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot include %s: %w", s.file, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot include %s: %w", s.file, unwrapPathError(err))
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	switch {
+	case s.region != "":
+		lines, err = regionLines(lines, s.region)
+	case s.lines != "":
+		lines, err = lineRange(lines, s.lines)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot include %s: %w", s.file, err)
+	}
+	return []byte(dedent(lines)), nil
+}
+
+// unwrapPathError drops the path from file system errors, which name the
+// resolved path rather than the one written in the document.
+func unwrapPathError(err error) error {
+	if pathErr, ok := err.(*os.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
+
+// lineRange returns the lines selected by spec: "15-40", "15-" for line 15
+// to the end, or "15". Lines count from 1.
+func lineRange(lines []string, spec string) ([]string, error) {
+	from, to, isRange := strings.Cut(spec, "-")
+	first, err := strconv.Atoi(from)
+	if err != nil || first < 1 {
+		return nil, fmt.Errorf("invalid lines '%s': use a range such as 15-40", spec)
+	}
+	last := first
+	if isRange {
+		last = len(lines)
+		if to != "" {
+			if last, err = strconv.Atoi(to); err != nil || last < first {
+				return nil, fmt.Errorf("invalid lines '%s': use a range such as 15-40", spec)
+			}
+		}
+	}
+	if last > len(lines) {
+		return nil, fmt.Errorf("lines %s are past the end of the file, which has %d lines", spec, len(lines))
+	}
+	return lines[first-1 : last], nil
+}
+
+// regionMarker matches the comments marking the start and end of a region,
+// such as // [START setup] and # [END setup].
+var regionMarker = regexp.MustCompile(`\[(START|END) ([^\]]+)\]`)
+
+// regionLines returns the lines between the [START name] and [END name]
+// markers, leaving out the marker lines of any other region inside.
+func regionLines(lines []string, name string) ([]string, error) {
+	start, end := -1, -1
+	for i, line := range lines {
+		match := regionMarker.FindStringSubmatch(line)
+		if match == nil || match[2] != name {
+			continue
+		}
+		if match[1] == "START" && start == -1 {
+			start = i
+		} else if match[1] == "END" && start != -1 {
+			end = i
+			break
+		}
+	}
+	switch {
+	case start == -1:
+		return nil, fmt.Errorf("region '%s' not found (mark it with [START %s] and [END %s])", name, name, name)
+	case end == -1:
+		return nil, fmt.Errorf("region '%s' has no [END %s] marker", name, name)
+	}
+
+	var region []string
+	for _, line := range lines[start+1 : end] {
+		if !regionMarker.MatchString(line) {
+			region = append(region, line)
+		}
+	}
+	return region, nil
+}
+
+// dedent joins lines, removing the leading whitespace they all share.
+func dedent(lines []string) string {
+	indent := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			indent, first = lead, false
+			continue
+		}
+		for !strings.HasPrefix(lead, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(strings.TrimPrefix(line, indent))
+	}
+	return b.String()
+}
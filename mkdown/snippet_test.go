@@ -0,0 +1,98 @@
+package mkdown
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCodeSnippets(t *testing.T) {
+	tmpDir := t.TempDir()
+	code := "package main\n\nfunc main() {\n\t// [START setup]\n\tx := 1\n\t// [START inner]\n\tx++\n\t// [END inner]\n\t// [END setup]\n}\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(markdown string) (string, error) {
+		inputPath := filepath.Join(tmpDir, "doc.md")
+		if err := os.WriteFile(inputPath, []byte(markdown), 0644); err != nil {
+			t.Fatal(err)
+		}
+		page, err := New(WithIncludes(true)).RenderFile(inputPath)
+		return string(page), err
+	}
+
+	page, err := render("```go file=main.go region=setup\n```\n\n```text {file=\"main.go\" lines=\"3\"}\nreplaced\n```\n")
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(page, `<span class="nx">x</span> <span class="o">:=</span>`) || !strings.Contains(page, "x</span><span class=\"o\">++") {
+		t.Error("expected the region to be included and highlighted")
+	}
+	if strings.Contains(page, "[START inner]") || strings.Contains(page, "replaced") {
+		t.Error("expected region markers and the block's own content to be left out")
+	}
+	if !strings.Contains(page, "func main() {\n") {
+		t.Error("expected line 3 to be included")
+	}
+
+	errorTests := []struct {
+		markdown string
+		want     string
+	}{
+		{"# Doc\n\n```go file=missing.go\n```\n", "doc.md:3: cannot include missing.go: no such file or directory"},
+		{"```go file=main.go region=teardown\n```\n", "doc.md:1: cannot include main.go: region 'teardown' not found"},
+		{"```go file=main.go lines=8-20\n```\n", "lines 8-20 are past the end of the file, which has 10 lines"},
+		{"```go lines=1-2\n```\n", "code block has lines or region but no file to include"},
+		{"```text file=/etc/passwd lines=1-2\n```\n", "doc.md:1: cannot include /etc/passwd: absolute paths are not allowed"},
+		{"```text file=../secret.txt\n```\n", "doc.md:1: cannot include ../secret.txt: outside " + tmpDir},
+	}
+	for _, tt := range errorTests {
+		if _, err := render(tt.markdown); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expected an error containing %q, got %v", tt.want, err)
+		}
+	}
+
+	if _, err := render("```go file=main.go\n```\n"); err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	deps, err := New(WithIncludes(true)).Dependencies(filepath.Join(tmpDir, "doc.md"))
+	if err != nil {
+		t.Fatalf("Dependencies failed: %v", err)
+	}
+	if len(deps) != 2 || deps[1] != filepath.Join(tmpDir, "main.go") {
+		t.Errorf("expected the included file among the dependencies, got %v", deps)
+	}
+
+	// Code blocks keep their own content unless includes are enabled.
+	inputPath := filepath.Join(tmpDir, "doc.md")
+	if err := os.WriteFile(inputPath, []byte("```text file=main.go\nown content\n```\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	disabled, err := New().RenderFile(inputPath)
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(disabled), "own content") || strings.Contains(string(disabled), "package main") {
+		t.Error("expected no file to be read by default")
+	}
+
+	// Input that did not come from a file needs a root, which paths are
+	// then relative to.
+	markdown := []byte("```go file=main.go lines=1\n```\n")
+	if _, err := New(WithIncludes(true)).ConvertBytes(markdown); err == nil || !strings.Contains(err.Error(), "include root") {
+		t.Errorf("expected byte input to need an include root, got %v", err)
+	}
+	output, err := New(WithIncludes(true), WithIncludeRoot(tmpDir)).ConvertBytes(markdown)
+	if err != nil || !strings.Contains(string(output), "package") {
+		t.Errorf("expected byte input to include from the root, got %v", err)
+	}
+}
+
+func TestDedent(t *testing.T) {
+	got := dedent([]string{"\t\tif x {\n", "\n", "\t\t\ty()\n", "\t\t}\n"})
+	if want := "if x {\n\n\ty()\n}\n"; got != want {
+		t.Errorf("dedent = %q, want %q", got, want)
+	}
+}
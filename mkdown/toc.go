@@ -0,0 +1,224 @@
+package mkdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// Default heading levels included in a table of contents.
+const (
+	DefaultTOCMinDepth = 2
+	DefaultTOCMaxDepth = 3
+)
+
+// KindTOC is the NodeKind of TOC nodes.
+var KindTOC = ast.NewNodeKind("TOC")
+
+// TOC is a table of contents placed where the document has a [TOC] or
+// <!-- toc --> marker.
+type TOC struct {
+	ast.BaseBlock
+	html string
+}
+
+// Kind implements ast.Node.Kind.
+func (n *TOC) Kind() ast.NodeKind {
+	return KindTOC
+}
+
+// Dump implements ast.Node.Dump.
+func (n *TOC) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// tocHeading is an entry of the table of contents.
+type tocHeading struct {
+	level int
+	id    string
+	text  string
+}
+
+// tocSettings decides whether and how a document gets a table of contents.
+type tocSettings struct {
+	enabled            bool
+	minDepth, maxDepth int
+}
+
+// documentTOCSettings applies the toc, toc_min_depth and toc_max_depth
+// frontmatter fields on top of the converter's settings.
+func (c *Converter) documentTOCSettings(metadata map[string]interface{}) tocSettings {
+	s := tocSettings{enabled: c.toc, minDepth: c.tocMinDepth, maxDepth: c.tocMaxDepth}
+	if enabled, ok := metadata["toc"].(bool); ok {
+		s.enabled = enabled
+	}
+	if depth, ok := metadata["toc_min_depth"].(int); ok {
+		s.minDepth = depth
+	}
+	if depth, ok := metadata["toc_max_depth"].(int); ok {
+		s.maxDepth = depth
+	}
+	if s.minDepth < 1 {
+		s.minDepth = DefaultTOCMinDepth
+	}
+	if s.maxDepth < 1 || s.maxDepth > 6 {
+		s.maxDepth = DefaultTOCMaxDepth
+		if s.minDepth > s.maxDepth {
+			s.maxDepth = s.minDepth
+		}
+	}
+	return s
+}
+
+// buildTOC replaces [TOC] and <!-- toc --> markers below root with the table
+// of contents. It returns the table for the page template when the settings
+// enable one and the document has no marker, or "" otherwise.
+func buildTOC(root ast.Node, source []byte, settings tocSettings) string {
+	var headings []tocHeading
+	var markers []ast.Node
+
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			id, _ := node.AttributeString("id")
+			idBytes, _ := id.([]byte)
+			if node.Level >= settings.minDepth && node.Level <= settings.maxDepth && len(idBytes) > 0 {
+				headings = append(headings, tocHeading{node.Level, string(idBytes), plainText(node, source)})
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph, *ast.HTMLBlock:
+			if isTOCMarker(n, source) {
+				markers = append(markers, n)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	list := tocList(headings)
+	for _, marker := range markers {
+		toc := &TOC{html: `<nav class="toc" aria-label="Table of contents">` + list + "</nav>"}
+		if list == "" {
+			toc.html = ""
+		}
+		marker.Parent().ReplaceChild(marker.Parent(), marker, toc)
+	}
+
+	if !settings.enabled || len(markers) > 0 {
+		return ""
+	}
+	return list
+}
+
+// isTOCMarker reports whether a paragraph or HTML block consists of only
+// [TOC] or <!-- toc -->.
+func isTOCMarker(n ast.Node, source []byte) bool {
+	var buf bytes.Buffer
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		buf.Write(line.Value(source))
+	}
+	text := strings.ToLower(strings.TrimSpace(buf.String()))
+
+	if _, ok := n.(*ast.Paragraph); ok {
+		return text == "[toc]"
+	}
+	text = strings.Join(strings.Fields(text), "")
+	return text == "<!--toc-->"
+}
+
+// tocList renders headings as nested lists, indenting each heading under
+// the closest preceding heading of a higher level.
+func tocList(headings []tocHeading) string {
+	if len(headings) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	var open []int // shallowest level in each list currently open
+	for i, h := range headings {
+		switch {
+		case i == 0:
+			b.WriteString("<ul>")
+			open = append(open, h.level)
+		case h.level > open[len(open)-1]:
+			b.WriteString("<ul>")
+			open = append(open, h.level)
+		default:
+			b.WriteString("</li>")
+			for len(open) > 1 && h.level < open[len(open)-1] && h.level <= open[len(open)-2] {
+				b.WriteString("</ul></li>")
+				open = open[:len(open)-1]
+			}
+			if h.level < open[len(open)-1] {
+				open[len(open)-1] = h.level
+			}
+		}
+		fmt.Fprintf(&b, `<li><a href="#%s">%s</a>`, html.EscapeString(h.id), html.EscapeString(h.text))
+	}
+	b.WriteString("</li>")
+	for range open[1:] {
+		b.WriteString("</ul></li>")
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
+
+// plainText returns the text of n's inline children without markup.
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := c.(type) {
+		case *ast.Text:
+			b.Write(node.Segment.Value(source))
+			if node.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			if node.IsCode() {
+				b.WriteString(html.UnescapeString(string(node.Value))) // typographer entity
+			} else {
+				b.Write(node.Value)
+			}
+		case *MathInline:
+			b.Write(mathText(node, source))
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.TrimSpace(b.String())
+}
+
+// tocHTMLRenderer renders TOC nodes.
+type tocHTMLRenderer struct{}
+
+func (r *tocHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTOC, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.(*TOC).html != "" {
+			_, _ = w.WriteString(n.(*TOC).html + "\n")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// tocExtension renders the TOC nodes inserted by buildTOC.
+type tocExtension struct{}
+
+func (e *tocExtension) Extend(m goldmark.Markdown) {
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&tocHTMLRenderer{}, 500),
+	))
+}
@@ -0,0 +1,106 @@
+package mkdown
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestTableOfContents(t *testing.T) {
+	body := "# Title\n\n## Intro\n\n### Details *here*\n\n## Usage\n\n#### Too deep\n"
+
+	tests := []struct {
+		name     string
+		opts     ConverterOptions
+		source   string
+		toc      string // expected Document.Toc
+		contains []string
+		excludes []string
+	}{
+		{
+			name:   "disabled by default",
+			source: body,
+		},
+		{
+			name:   "option enables sidebar",
+			opts:   ConverterOptions{TOC: true},
+			source: body,
+			toc:    `<ul><li><a href="#intro">Intro</a><ul><li><a href="#details-here">Details here</a></li></ul></li><li><a href="#usage">Usage</a></li></ul>`,
+		},
+		{
+			name:   "frontmatter enables sidebar",
+			source: "---\ntoc: true\ntoc_max_depth: 2\n---\n" + body,
+			toc:    `<ul><li><a href="#intro">Intro</a></li><li><a href="#usage">Usage</a></li></ul>`,
+		},
+		{
+			name:   "frontmatter disables sidebar",
+			opts:   ConverterOptions{TOC: true},
+			source: "---\ntoc: false\n---\n" + body,
+		},
+		{
+			name:     "marker places the table in the content",
+			opts:     ConverterOptions{TOC: true},
+			source:   "[TOC]\n\n" + body,
+			contains: []string{`<nav class="toc" aria-label="Table of contents"><ul><li><a href="#intro">Intro</a>`},
+			excludes: []string{"[TOC]"},
+		},
+		{
+			name:     "comment marker with depth options",
+			opts:     ConverterOptions{TOCMinDepth: 1, TOCMaxDepth: 4},
+			source:   "<!-- toc -->\n\n" + body,
+			contains: []string{`<li><a href="#title">Title</a><ul><li><a href="#intro">Intro</a>`, `<a href="#too-deep">Too deep</a>`},
+			excludes: []string{"<!-- toc -->"},
+		},
+		{
+			name:     "marker text inside a sentence is left alone",
+			source:   "See [TOC] below.\n\n" + body,
+			contains: []string{"See [TOC] below."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := NewConverterWithOptions(tt.opts).RenderDocument(context.Background(), []byte(tt.source))
+			if err != nil {
+				t.Fatalf("RenderDocument failed: %v", err)
+			}
+			if string(doc.Toc) != tt.toc {
+				t.Errorf("Toc = %s\nwant %s", doc.Toc, tt.toc)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(doc.Content), want) {
+					t.Errorf("expected %q in:\n%s", want, doc.Content)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(string(doc.Content), unwanted) {
+					t.Errorf("did not expect %q in:\n%s", unwanted, doc.Content)
+				}
+			}
+			hasScript := strings.Contains(string(doc.Scripts), "nav.toc")
+			if wantScript := tt.toc != "" || len(tt.excludes) > 0; hasScript != wantScript {
+				t.Errorf("scrollspy script included = %v, want %v", hasScript, wantScript)
+			}
+		})
+	}
+}
+
+func TestTOCSidebarTemplate(t *testing.T) {
+	output, err := New(WithTOC(true)).ConvertBytes([]byte("## One\n\n## Two"))
+	if err != nil {
+		t.Fatalf("ConvertBytes failed: %v", err)
+	}
+	for _, want := range []string{`<body class="has-toc">`, `<nav class="toc toc-sidebar"`, "<main>"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("expected %q in page", want)
+		}
+	}
+}
+
+func TestTOCList(t *testing.T) {
+	got := tocList([]tocHeading{{3, "a", "A"}, {2, "b", "B"}, {4, "c", "C"}, {3, "d", "D"}, {2, "e", "E <&>"}})
+	want := `<ul><li><a href="#a">A</a></li><li><a href="#b">B</a><ul><li><a href="#c">C</a></li><li><a href="#d">D</a></li></ul></li><li><a href="#e">E &lt;&amp;&gt;</a></li></ul>`
+	if got != want {
+		t.Errorf("tocList =\n%s\nwant\n%s", got, want)
+	}
+}
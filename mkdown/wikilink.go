@@ -0,0 +1,336 @@
+package mkdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindWikiLink is the NodeKind of WikiLink nodes.
+var KindWikiLink = ast.NewNodeKind("WikiLink")
+
+// WikiLink is an Obsidian-style link to another page by name:
+// [[Page Name]], [[Page#Heading]], [[#Heading]] or [[Page|label]].
+type WikiLink struct {
+	ast.BaseInline
+	// Page is the name of the linked page, or "" for a heading on the
+	// same page.
+	Page string
+	// Heading is the heading linked to, or "".
+	Heading string
+	// Label is the text shown for the link.
+	Label string
+	// Destination is the URL of the page, set when the link is resolved.
+	Destination string
+
+	offset int    // byte offset of the link in the source
+	file   string // markdown file of the linked page, once resolved
+}
+
+// Kind implements ast.Node.Kind.
+func (n *WikiLink) Kind() ast.NodeKind {
+	return KindWikiLink
+}
+
+// Dump implements ast.Node.Dump.
+func (n *WikiLink) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Page":        n.Page,
+		"Heading":     n.Heading,
+		"Label":       n.Label,
+		"Destination": n.Destination,
+	}, nil)
+}
+
+// slugify returns the identifier goldmark gives a heading with the text s,
+// which is also how page names are matched to files: case-insensitively,
+// with spaces, hyphens and underscores alike and other punctuation ignored.
+func slugify(s string) string {
+	return string(parser.NewContext().IDs().Generate([]byte(s), ast.KindHeading))
+}
+
+// wikiLinkParser parses [[...]] before the link parser sees the brackets.
+type wikiLinkParser struct{}
+
+func (p *wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (p *wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("]]"))
+	if end == -1 {
+		return nil
+	}
+	inner := string(line[2:end])
+	if strings.TrimSpace(inner) == "" || strings.ContainsAny(inner, "[]") {
+		return nil
+	}
+
+	target, label, hasLabel := strings.Cut(inner, "|")
+	page, heading, _ := strings.Cut(target, "#")
+	node := &WikiLink{
+		Page:    strings.TrimSpace(page),
+		Heading: strings.TrimSpace(heading),
+		Label:   strings.TrimSpace(label),
+		offset:  segment.Start,
+	}
+	if !hasLabel || node.Label == "" {
+		switch {
+		case node.Page == "":
+			node.Label = node.Heading
+		case node.Heading == "":
+			node.Label = node.Page
+		default:
+			node.Label = node.Page + " > " + node.Heading
+		}
+	}
+
+	block.Advance(end + 2)
+	return node
+}
+
+// wikiPages indexes the markdown files below a directory by the slug of
+// their name and of their path without the extension.
+type wikiPages map[string][]string
+
+// findWikiPages returns the markdown files in root, and below it when
+// recursive, skipping hidden and unreadable directories.
+func findWikiPages(root string, recursive bool) wikiPages {
+	pages := wikiPages{}
+	_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (!recursive || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		name := strings.TrimSuffix(rel, filepath.Ext(rel))
+		keys := []string{slugify(filepath.Base(name))}
+		if full := slugPath(filepath.ToSlash(name)); full != keys[0] {
+			keys = append(keys, full)
+		}
+		for _, key := range keys {
+			pages[key] = append(pages[key], path)
+		}
+		return nil
+	})
+	return pages
+}
+
+// wikiPagesMaxAge is how long an index of pages is reused before its
+// directory is walked again: a batch run walks it once, while watch mode
+// and the preview server still notice pages being added and removed.
+const wikiPagesMaxAge = 2 * time.Second
+
+// wikiPageCache holds the page indexes built for each directory.
+type wikiPageCache struct {
+	mu      sync.Mutex
+	entries map[wikiPageSearch]wikiPageIndex
+}
+
+// wikiPageSearch is a directory searched for pages.
+type wikiPageSearch struct {
+	root      string // absolute
+	recursive bool
+}
+
+// wikiPageIndex is an index of pages and when it was built.
+type wikiPageIndex struct {
+	pages wikiPages
+	built time.Time
+}
+
+// pages returns the index of the pages in root, and below it when
+// recursive, walking the directory unless a recent index is cached.
+func (cache *wikiPageCache) pages(root string, recursive bool) wikiPages {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	search := wikiPageSearch{root: root, recursive: recursive}
+	if index, ok := cache.entries[search]; ok && time.Since(index.built) < wikiPagesMaxAge {
+		return index.pages
+	}
+	if cache.entries == nil {
+		cache.entries = make(map[wikiPageSearch]wikiPageIndex)
+	}
+	pages := findWikiPages(root, recursive)
+	cache.entries[search] = wikiPageIndex{pages: pages, built: time.Now()}
+	return pages
+}
+
+// wikiPagesRoot returns the directory searched for the pages named by the
+// wikilinks of the document at path, and whether its subdirectories are
+// searched too. It is the WikiLinkRoot option, or else the document's
+// directory. A filesystem root is never searched recursively, and neither
+// is the working directory for input that did not come from a file.
+func (c *Converter) wikiPagesRoot(path string) (string, bool) {
+	if c.wikiLinkRoot != "" && !isFilesystemRoot(c.wikiLinkRoot) {
+		return c.wikiLinkRoot, true
+	}
+	dir := baseDir(path)
+	return dir, path != "" && !isFilesystemRoot(dir)
+}
+
+// isFilesystemRoot reports whether dir is / or a volume root.
+func isFilesystemRoot(dir string) bool {
+	abs, err := filepath.Abs(dir)
+	return err == nil && filepath.Dir(abs) == abs
+}
+
+// slugPath slugifies each segment of a slash-separated page path.
+func slugPath(name string) string {
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = slugify(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// find returns the file a page name refers to. When several files have the
+// name, the one in fromDir wins, then the one with the shortest path.
+func (p wikiPages) find(name, fromDir string) (string, bool) {
+	key := slugify(name)
+	if strings.Contains(name, "/") {
+		key = slugPath(strings.TrimSuffix(name, filepath.Ext(name)))
+	} else if ext := strings.ToLower(filepath.Ext(name)); ext == ".md" || ext == ".markdown" {
+		key = slugify(strings.TrimSuffix(name, filepath.Ext(name)))
+	}
+	candidates := p[key]
+	if len(candidates) == 0 {
+		return "", false
+	}
+	sorted := append([]string(nil), candidates...)
+	sort.Slice(sorted, func(i, j int) bool {
+		iHere, jHere := filepath.Dir(sorted[i]) == fromDir, filepath.Dir(sorted[j]) == fromDir
+		if iHere != jHere {
+			return iHere
+		}
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) < len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	return sorted[0], true
+}
+
+// resolveWikiLinks points the wikilinks below root at the HTML pages
+// generated from the markdown files they name, found as described by
+// wikiPagesRoot, and returns a warning for each link to a page that does
+// not exist. path is the document, whose directory is searched first;
+// linker makes the links; where describes the position of an offset in the
+// source for warnings.
+func (c *Converter) resolveWikiLinks(root ast.Node, path string, linker *pageLinker, where func(offset int) string) ([]string, error) {
+	var links []*WikiLink
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if link, ok := n.(*WikiLink); ok && entering {
+			links = append(links, link)
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(links) == 0 {
+		return nil, nil
+	}
+
+	// Compare absolute paths, as the document and the root may be given
+	// differently.
+	pagesRoot, recursive := c.wikiPagesRoot(path)
+	absRoot, err := filepath.Abs(pagesRoot)
+	if err != nil {
+		return nil, err
+	}
+	fromDir, err := filepath.Abs(baseDir(path))
+	if err != nil {
+		return nil, err
+	}
+	pages := c.wikiPages.pages(absRoot, recursive)
+
+	var warnings []string
+	for _, link := range links {
+		fragment := ""
+		if link.Heading != "" {
+			fragment = "#" + slugify(link.Heading)
+		}
+		if link.Page == "" {
+			link.Destination = fragment
+			continue
+		}
+
+		file, ok := pages.find(link.Page, fromDir)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("wikilink (%s): no page named '%s' in %s", where(link.offset), link.Page, pagesRoot))
+			continue
+		}
+		page, err := linker.link(file)
+		if err != nil {
+			return nil, err
+		}
+		link.Destination = page + fragment
+		link.file = file
+	}
+	return warnings, nil
+}
+
+// wikiLinkHTMLRenderer renders resolved wikilinks as links and unresolved
+// ones as a span, so they stand out without leading nowhere.
+type wikiLinkHTMLRenderer struct{}
+
+func (r *wikiLinkHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindWikiLink, r.renderWikiLink)
+}
+
+func (r *wikiLinkHTMLRenderer) renderWikiLink(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	link := n.(*WikiLink)
+	label := html.EscapeString(link.Label)
+	if link.Destination == "" {
+		_, _ = w.WriteString(`<span class="wikilink wikilink-missing">` + label + "</span>")
+	} else {
+		_, _ = w.WriteString(`<a class="wikilink" href="` + html.EscapeString(link.Destination) + `">` + label + "</a>")
+	}
+	return ast.WalkSkipChildren, nil
+}
+
+// wikiLinkExtension adds [[wikilinks]] to goldmark.
+type wikiLinkExtension struct{}
+
+func (e *wikiLinkExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithInlineParsers(util.Prioritized(&wikiLinkParser{}, 199)),
+	)
+	m.Renderer().AddOptions(renderer.WithNodeRenderers(
+		util.Prioritized(&wikiLinkHTMLRenderer{}, 500),
+	))
+}
@@ -0,0 +1,103 @@
+package mkdown
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWikiLinks(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"notes/index.md":         "# Index\n\n[[Page Name]] [[page name#Second Heading]] [[Other|see other]] [[#Local]] [[deep/other]] [[Missing]] [[Hidden]] `[[code]]`\n\n## Local\n",
+		"notes/Page Name.md":     "# Page Name\n\n## Second Heading\n",
+		"notes/other.md":         "# Other here\n",
+		"notes/deep/other.md":    "# Other deep\n",
+		"notes/.trash/hidden.md": "# Hidden\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var warnings []string
+	c := NewConverterWithOptions(ConverterOptions{WikiLinks: true, WarningHandler: func(path, message string) {
+		warnings = append(warnings, message)
+	}})
+	page, err := c.RenderFile(filepath.Join(tmpDir, "notes", "index.md"))
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	output := string(page)
+
+	for _, want := range []string{
+		`<a class="wikilink" href="Page%20Name.html">Page Name</a>`,
+		`<a class="wikilink" href="Page%20Name.html#second-heading">page name &gt; Second Heading</a>`,
+		`<a class="wikilink" href="other.html">see other</a>`,
+		`<a class="wikilink" href="#local">Local</a>`,
+		`<a class="wikilink" href="deep/other.html">deep/other</a>`,
+		`<span class="wikilink wikilink-missing">Missing</span>`,
+		`<span class="wikilink wikilink-missing">Hidden</span>`,
+		`<code>[[code]]</code>`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "wikilink (line 3): no page named 'Missing'") {
+		t.Errorf("expected warnings for the missing pages, got %v", warnings)
+	}
+
+	// With a wider root, pages in other directories resolve relative to the
+	// document.
+	c = NewConverterWithOptions(ConverterOptions{WikiLinks: true, WikiLinkRoot: tmpDir, WarningHandler: func(string, string) {}})
+	page, err = c.RenderFile(filepath.Join(tmpDir, "notes", "deep", "other.md"))
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(page), "Other deep") {
+		t.Error("expected the page to render")
+	}
+	doc, err := c.RenderDocument(context.Background(), []byte("[[Page Name]]"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if strings.Contains(string(doc.Content), "wikilink-missing") {
+		t.Errorf("expected the link to resolve against the root:\n%s", doc.Content)
+	}
+
+	// A filesystem root is never searched: the document's own directory is
+	// used instead, and directories that cannot be read are skipped.
+	locked := filepath.Join(tmpDir, "notes", "locked")
+	if err := os.MkdirAll(locked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0755)
+	c = New(WithWikiLinks(true), WithWikiLinkRoot(string(filepath.Separator)), WithWarningHandler(func(string, string) {}))
+	page, err = c.RenderFile(filepath.Join(tmpDir, "notes", "index.md"))
+	if err != nil {
+		t.Fatalf("RenderFile failed: %v", err)
+	}
+	if !strings.Contains(string(page), `<a class="wikilink" href="Page%20Name.html">Page Name</a>`) {
+		t.Errorf("expected the link to resolve in the document's directory:\n%s", page)
+	}
+
+	// Wikilinks are left alone unless enabled.
+	doc, err = New().RenderDocument(context.Background(), []byte("[[Page Name]]"))
+	if err != nil {
+		t.Fatalf("RenderDocument failed: %v", err)
+	}
+	if !strings.Contains(string(doc.Content), "[[Page Name]]") || strings.Contains(string(doc.Content), "wikilink") {
+		t.Errorf("expected wikilinks to stay text by default:\n%s", doc.Content)
+	}
+}